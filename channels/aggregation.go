@@ -1,10 +1,13 @@
 // Copyright 2022 Jesse Waite
 
-// aggregation.go contains patterns for combining channels: fan-in, etc.
+// aggregation.go contains patterns for combining and distributing channels: fan-in, fan-out, etc.
 
 package channerics
 
-import "sync"
+import (
+	"hash/fnv"
+	"sync"
+)
 
 // Merge merges multiple channels into a single output chan, also often called
 // 'Fan In'. The returned channel closes if done is closed, or if
@@ -12,13 +15,21 @@ import "sync"
 func Merge[T any](
 	done <-chan struct{},
 	inputs ...<-chan T,
+) <-chan T {
+	return merge(goAsync, done, inputs...)
+}
+
+func merge[T any](
+	spawn spawner,
+	done <-chan struct{},
+	inputs ...<-chan T,
 ) <-chan T {
 	out := make(chan T)
 	var wg sync.WaitGroup
 
 	multiplex := func(in <-chan T) {
 		defer wg.Done()
-		for v := range OrDone(done, in) {
+		for v := range orDone(spawn, done, in) {
 			select {
 			case out <- v:
 			case <-done:
@@ -30,7 +41,8 @@ func Merge[T any](
 	// Multiplex the inputs.
 	wg.Add(len(inputs))
 	for _, in := range inputs {
-		go multiplex(in)
+		in := in
+		spawn(func() { multiplex(in) })
 	}
 
 	// Await done or closure of all inputs.
@@ -38,30 +50,204 @@ func Merge[T any](
 		wg.Wait()
 		close(out)
 	}
-	go closer()
+	spawn(closer)
 
 	return out
 }
 
-/*
-// FanOut takes a T chan generator, wraps each generated channel with OrDone,
-// and returns the slice of chans. This is little more than a convenience function
-// for creating a slice of channels that abide the or-done pattern, with the caller
-// responsible for each chan's behavior. There doesn't seem to be a more helpful
-// FanOut method, since most of the fan-out pattern is implemented on the user side.
-// TBD: think of a more useful FanOut method. This isn't even really a FanOut
-// since there are no go statements below. Write an example.
+// Bridge flattens a stream of channels into a single output channel, reading each
+// channel to completion before moving on to the next, such that the order of values
+// is preserved. The returned channel closes if done is closed, or if chanStream and
+// the last channel received from it are both closed. Like OrDone, done-guards give
+// done's closure precedence over any channel that is ready to be read.
+func Bridge[T any](
+	done <-chan struct{},
+	chanStream <-chan <-chan T,
+) <-chan T {
+	return bridge(goAsync, done, chanStream)
+}
+
+func bridge[T any](
+	spawn spawner,
+	done <-chan struct{},
+	chanStream <-chan <-chan T,
+) <-chan T {
+	out := make(chan T)
+
+	spawn(func() {
+		defer close(out)
+		for stream := range orDone(spawn, done, chanStream) {
+			for v := range orDone(spawn, done, stream) {
+				// Done-guard: OrDone may have received v just prior to done's closure.
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				select {
+				case out <- v:
+				case <-done:
+					return
+				}
+			}
+		}
+	})
+
+	return out
+}
+
+// Concat is the fixed-length counterpart to Bridge: it streams the values of each
+// passed channel in order, reading each to completion before the next.
+// The returned channel closes if done is closed, or if all chans are closed and drained.
+func Concat[T any](
+	done <-chan struct{},
+	chans ...<-chan T,
+) <-chan T {
+	return concat(goAsync, done, chans...)
+}
+
+func concat[T any](
+	spawn spawner,
+	done <-chan struct{},
+	chans ...<-chan T,
+) <-chan T {
+	chanStream := make(chan (<-chan T), len(chans))
+	for _, ch := range chans {
+		chanStream <- ch
+	}
+	close(chanStream)
+
+	return bridge(spawn, done, chanStream)
+}
+
+// A FanOutStrategy decides which of FanOut's n outputs receives each item. It is called
+// once per FanOut call and returns a selector that maps an item to an output index in [0, n).
+// Selectors are only called from a single goroutine and may thus keep unsynchronized state.
+// A nil selector indicates that each item goes to whichever output is first available.
+// A selector that returns an index outside [0, n) ends the distribution as if done were
+// closed: all outputs close, and the offending item and the remainder of in are not read.
+type FanOutStrategy[T any] func(n int) (selector func(item T) int)
+
+// RoundRobin returns a FanOutStrategy that cycles through the outputs in order.
+func RoundRobin[T any]() FanOutStrategy[T] {
+	return func(n int) func(T) int {
+		next := -1
+		return func(T) int {
+			next = (next + 1) % n
+			return next
+		}
+	}
+}
+
+// FirstAvailable returns a FanOutStrategy that sends each item to whichever output
+// is first ready to accept it, such that slow consumers receive fewer items.
+func FirstAvailable[T any]() FanOutStrategy[T] {
+	return func(int) func(T) int {
+		return nil
+	}
+}
+
+// KeyAffinity returns a FanOutStrategy that hashes the key of each item, such that
+// items with equal keys are always sent to the same output, and thus in order.
+func KeyAffinity[T any](keyFn func(T) string) FanOutStrategy[T] {
+	return func(n int) func(T) int {
+		return func(item T) int {
+			h := fnv.New32a()
+			h.Write([]byte(keyFn(item)))
+			return int(h.Sum32() % uint32(n))
+		}
+	}
+}
+
+// FanOut distributes the values of in across n output channels per strategy, such that
+// each value is sent to exactly one output. It is the counterpart to Merge; together they
+// form the fan-out/fan-in pattern:
+//
+//	outputs := FanOut(done, jobs, 4, FirstAvailable[Job]())
+//	results := make([]<-chan Result, len(outputs))
+//	for i, output := range outputs {
+//		results[i] = Convert(done, output, process)
+//	}
+//	for result := range Merge(done, results...) {
+//		...
+//	}
+//
+// All outputs close when done is closed, or once in is closed and drained.
+// If n <= 0 there are no outputs to distribute to, so FanOut returns nil and never reads in.
+// A nil strategy is equivalent to FirstAvailable.
+// For strategies other than FirstAvailable, a single slow consumer blocks the others,
+// similar to Broadcast. With FirstAvailable, each output may hold one value received from
+// in while awaiting its consumer, but otherwise never blocks the others.
 func FanOut[T any](
 	done <-chan struct{},
-	next func() chan T,
+	in <-chan T,
+	n int,
+	strategy FanOutStrategy[T],
+) []<-chan T {
+	return fanOut(goAsync, done, in, n, strategy)
+}
+
+func fanOut[T any](
+	spawn spawner,
+	done <-chan struct{},
+	in <-chan T,
+	n int,
+	strategy FanOutStrategy[T],
 ) (outputs []<-chan T) {
-	ch := next()
-	for ch != nil {
-		orDone := OrDone(done, ch)
-		outputs = append(outputs, orDone)
-		ch = next()
+	if n <= 0 {
+		return nil
+	}
+
+	outChans := make([]chan T, n)
+	for i := 0; i < n; i++ {
+		outChans[i] = make(chan T)
+		outputs = append(outputs, outChans[i])
+	}
+
+	var selector func(T) int
+	if strategy != nil {
+		selector = strategy(n)
+	}
+	if selector == nil {
+		// First-available: each output competes to receive from the input as soon
+		// as its consumer has taken the previous value.
+		input := orDone(spawn, done, in)
+		for _, outChan := range outChans {
+			outChan := outChan
+			spawn(func() {
+				defer close(outChan)
+				for v := range input {
+					select {
+					case outChan <- v:
+					case <-done:
+						return
+					}
+				}
+			})
+		}
+		return
 	}
 
+	spawn(func() {
+		defer func() {
+			for _, outChan := range outChans {
+				close(outChan)
+			}
+		}()
+
+		for v := range orDone(spawn, done, in) {
+			i := selector(v)
+			if i < 0 || i >= n {
+				return
+			}
+			select {
+			case outChans[i] <- v:
+			case <-done:
+				return
+			}
+		}
+	})
+
 	return
 }
-*/
@@ -3,6 +3,7 @@
 package channerics
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -195,3 +196,371 @@ func TestMerge(t *testing.T) {
 		})
 	})
 }
+
+func TestBridge(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	Convey("Bridge tests", t, func() {
+		Convey("When done is already closed", func() {
+			done := make(chan struct{})
+			chanStream := make(chan (<-chan int))
+			close(done)
+
+			out := Bridge(done, chanStream)
+
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When the chan stream is closed before any channel is sent", func() {
+			done := make(chan struct{})
+			chanStream := make(chan (<-chan int))
+			close(chanStream)
+
+			out := Bridge(done, chanStream)
+
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When done is closed while an inner channel is still open", func() {
+			done := make(chan struct{})
+			chanStream := make(chan (<-chan int))
+			inner := make(chan int)
+			out := Bridge(done, chanStream)
+
+			go func() {
+				chanStream <- inner
+				inner <- 1
+			}()
+
+			val := 0
+			select {
+			case val = <-out:
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(val, ShouldEqual, 1)
+
+			close(done)
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When done is closed while a value awaits its consumer", func() {
+			done := make(chan struct{})
+			chanStream := make(chan (<-chan int), 1)
+			inner := make(chan int, 1)
+			inner <- 1
+			chanStream <- inner
+			out := Bridge(done, chanStream)
+
+			// Let Bridge block on sending the value, then close done instead of reading it.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			close(done)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When several channels are bridged -- happy path", func() {
+			done := make(chan struct{})
+			defer close(done)
+			chanStream := make(chan (<-chan int))
+			out := Bridge(done, chanStream)
+
+			go func() {
+				defer close(chanStream)
+				for i := 0; i < 3; i++ {
+					ch := make(chan int, 2)
+					ch <- i * 2
+					ch <- i*2 + 1
+					close(ch)
+					chanStream <- ch
+				}
+			}()
+
+			results := []int{}
+			for v := range out {
+				results = append(results, v)
+			}
+			So(results, ShouldResemble, []int{0, 1, 2, 3, 4, 5})
+		})
+	})
+}
+
+func TestConcat(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	Convey("Concat tests", t, func() {
+		Convey("When no channels are passed", func() {
+			done := make(chan struct{})
+			out := Concat[int](done)
+
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When several channels are concatenated -- happy path", func() {
+			done := make(chan struct{})
+			defer close(done)
+			ch1 := make(chan string, 2)
+			ch2 := make(chan string, 1)
+			ch1 <- "a"
+			ch1 <- "b"
+			ch2 <- "c"
+			close(ch1)
+			close(ch2)
+
+			results := []string{}
+			for v := range Concat(done, ch1, ch2) {
+				results = append(results, v)
+			}
+			So(results, ShouldResemble, []string{"a", "b", "c"})
+		})
+	})
+}
+
+func TestFanOut(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	Convey("FanOut tests", t, func() {
+		Convey("When done is already closed", func() {
+			strategies := []FanOutStrategy[int]{
+				RoundRobin[int](),
+				FirstAvailable[int](),
+				KeyAffinity(func(i int) string { return fmt.Sprint(i) }),
+			}
+			for _, strategy := range strategies {
+				done := make(chan struct{})
+				in := make(chan int)
+				close(done)
+
+				outputs := FanOut(done, in, 3, strategy)
+				So(len(outputs), ShouldEqual, 3)
+				for _, output := range outputs {
+					chanClosed := false
+					select {
+					case _, ok := <-output:
+						chanClosed = !ok
+					case <-time.After(maxWaitForEffect):
+						t.FailNow()
+					}
+					So(chanClosed, ShouldBeTrue)
+				}
+			}
+		})
+
+		Convey("When input is closed all outputs close", func() {
+			strategies := []FanOutStrategy[int]{
+				RoundRobin[int](),
+				FirstAvailable[int](),
+			}
+			for _, strategy := range strategies {
+				done := make(chan struct{})
+				in := make(chan int)
+				close(in)
+
+				outputs := FanOut(done, in, 2, strategy)
+				for _, output := range outputs {
+					chanClosed := false
+					select {
+					case _, ok := <-output:
+						chanClosed = !ok
+					case <-time.After(maxWaitForEffect):
+						t.FailNow()
+					}
+					So(chanClosed, ShouldBeTrue)
+				}
+			}
+		})
+
+		Convey("When RoundRobin is used", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			outputs := FanOut(done, in, 3, RoundRobin[int]())
+
+			go func() {
+				for i := 0; i < 6; i++ {
+					in <- i
+				}
+			}()
+
+			for i := 0; i < 6; i++ {
+				select {
+				case v := <-outputs[i%3]:
+					So(v, ShouldEqual, i)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+		})
+
+		Convey("When KeyAffinity is used equal keys go to the same output", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan string)
+			keyFn := func(s string) string { return s[:1] }
+			outputs := FanOut(done, in, 4, KeyAffinity(keyFn))
+			selector := KeyAffinity(keyFn)(4)
+
+			items := []string{"a1", "b1", "a2", "c1", "b2", "a3"}
+			go func() {
+				for _, item := range items {
+					in <- item
+				}
+			}()
+
+			for _, item := range items {
+				select {
+				case v := <-outputs[selector(item)]:
+					So(v, ShouldEqual, item)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+		})
+
+		Convey("When FirstAvailable is used with fan-in via Merge -- happy path", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			outputs := FanOut(done, in, 4, FirstAvailable[int]())
+
+			go func() {
+				defer close(in)
+				for i := 0; i < 100; i++ {
+					in <- i
+				}
+			}()
+
+			sum := 0
+			count := 0
+			for v := range Merge(done, outputs...) {
+				sum += v
+				count++
+			}
+			So(count, ShouldEqual, 100)
+			So(sum, ShouldEqual, 4950)
+		})
+
+		Convey("When FirstAvailable is used a blocked consumer does not block the others", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			outputs := FanOut(done, in, 2, FirstAvailable[int]())
+
+			go func() {
+				for i := 0; i < 10; i++ {
+					in <- i
+				}
+			}()
+
+			// Only outputs[1] is read; outputs[0] may hold at most one item.
+			count := 0
+			for count < 9 {
+				select {
+				case <-outputs[1]:
+					count++
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			So(count, ShouldEqual, 9)
+		})
+
+		Convey("When the strategy is nil the first available output receives each item", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int, 3)
+			in <- 1
+			in <- 2
+			in <- 3
+			close(in)
+			outputs := FanOut(done, in, 2, nil)
+			So(len(outputs), ShouldEqual, 2)
+
+			count := 0
+			for v := range Merge(done, outputs...) {
+				So(v, ShouldBeIn, 1, 2, 3)
+				count++
+			}
+			So(count, ShouldEqual, 3)
+		})
+
+		Convey("When the selector returns an index out of range the outputs close", func() {
+			for _, index := range []int{-1, 2} {
+				index := index
+				done := make(chan struct{})
+				in := make(chan int, 3)
+				in <- 1
+				in <- 2
+				in <- 3
+				close(in)
+				strategy := func(n int) func(int) int {
+					return func(v int) int {
+						if v == 2 {
+							return index
+						}
+						return 0
+					}
+				}
+				outputs := FanOut(done, in, 2, strategy)
+
+				values := []int{}
+				for v := range Merge(done, outputs...) {
+					values = append(values, v)
+				}
+				So(values, ShouldResemble, []int{1})
+				close(done)
+			}
+		})
+
+		Convey("When n is not positive there are no outputs and in is not read", func() {
+			strategies := []FanOutStrategy[int]{
+				RoundRobin[int](),
+				FirstAvailable[int](),
+				KeyAffinity(func(i int) string { return fmt.Sprint(i) }),
+			}
+			for _, strategy := range strategies {
+				for _, n := range []int{0, -1} {
+					in := make(chan int, 1)
+					in <- 1
+					So(FanOut(nil, in, n, strategy), ShouldBeNil)
+					So(len(in), ShouldEqual, 1)
+				}
+			}
+		})
+	})
+}
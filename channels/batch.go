@@ -0,0 +1,109 @@
+// Copyright 2022 Jesse Waite
+
+// batch.go contains patterns for grouping stream values into batches.
+
+package channerics
+
+import "time"
+
+// Batch groups values from in into slices, flushing the current batch when it reaches
+// maxSize items, when maxWait has elapsed since the batch's first item was received,
+// or when in is closed, in which case the final partial batch is sent before the output
+// closes. Empty batches are never sent. A maxSize <= 0 disables size-based flushes,
+// and a maxWait <= 0 disables time-based flushes.
+// The returned channel closes immediately if done is closed, discarding any partial
+// batch; thus callers that require the final batch should close in, not done, and then
+// drain the output. Like OrDone, a done-guard gives done precedence over in.
+func Batch[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	maxSize int,
+	maxWait time.Duration,
+) <-chan []T {
+	return batch(goAsync, done, clock, in, maxSize, maxWait)
+}
+
+func batch[T any](
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	maxSize int,
+	maxWait time.Duration,
+) <-chan []T {
+	out := make(chan []T)
+
+	spawn(func() {
+		defer close(out)
+
+		var batch []T
+		var timer Timer
+		// expired is nil, and thus blocks forever, whenever no timer is pending.
+		var expired <-chan time.Time
+		stopTimer := func() {
+			if timer != nil {
+				timer.Stop()
+				timer = nil
+				expired = nil
+			}
+		}
+		defer stopTimer()
+
+		// flush sends the current batch and returns false if done was closed.
+		flush := func() bool {
+			stopTimer()
+			b := batch
+			batch = nil
+
+			select {
+			case <-done:
+				return false
+			default:
+			}
+
+			select {
+			case out <- b:
+				return true
+			case <-done:
+				return false
+			}
+		}
+
+		for {
+			// Done-guard: give done precedence over in and expired.
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						flush()
+					}
+					return
+				}
+
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = clock.NewTimer(maxWait)
+					expired = timer.C()
+				}
+				if maxSize > 0 && len(batch) >= maxSize && !flush() {
+					return
+				}
+			case <-expired:
+				if !flush() {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	})
+
+	return out
+}
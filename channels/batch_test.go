@@ -0,0 +1,139 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBatch(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("Batch tests", t, func() {
+		Convey("When done is already closed", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			close(done)
+
+			out := Batch(done, NewFakeClock(epoch), in, 2, time.Second)
+
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When input is closed before any value is sent", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			close(in)
+
+			out := Batch(done, NewFakeClock(epoch), in, 2, time.Second)
+
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+
+		Convey("When batches are flushed by size", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			out := Batch(done, NewFakeClock(epoch), in, 2, time.Hour)
+
+			go func() {
+				for i := 0; i < 4; i++ {
+					in <- i
+				}
+			}()
+
+			for _, expected := range [][]int{{0, 1}, {2, 3}} {
+				select {
+				case batch := <-out:
+					So(batch, ShouldResemble, expected)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+		})
+
+		Convey("When a batch is flushed by time", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			maxWait := time.Duration(10) * time.Millisecond
+			out := Batch(done, clock, in, 10, maxWait)
+
+			in <- 1
+			in <- 2
+
+			// The timer was armed upon receiving the first item, before the second was received.
+			clock.Advance(maxWait - 1)
+			select {
+			case <-out:
+				t.FailNow()
+			case <-time.After(time.Duration(10) * time.Millisecond):
+			}
+
+			clock.Advance(1)
+			select {
+			case batch := <-out:
+				So(batch, ShouldResemble, []int{1, 2})
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When input is closed the final partial batch is sent", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			out := Batch(done, NewFakeClock(epoch), in, 10, 0)
+
+			go func() {
+				in <- 1
+				in <- 2
+				in <- 3
+				close(in)
+			}()
+
+			batches := [][]int{}
+			for batch := range out {
+				batches = append(batches, batch)
+			}
+			So(batches, ShouldResemble, [][]int{{1, 2, 3}})
+		})
+
+		Convey("When done is closed with a partial batch pending", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			out := Batch(done, NewFakeClock(epoch), in, 10, time.Hour)
+
+			in <- 1
+			close(done)
+
+			chanClosed := false
+			select {
+			case _, ok := <-out:
+				chanClosed = !ok
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(chanClosed, ShouldBeTrue)
+		})
+	})
+}
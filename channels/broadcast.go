@@ -14,6 +14,15 @@ func Broadcast[T any](
 	done <-chan struct{},
 	input <-chan T,
 	n int,
+) (outputs []<-chan T) {
+	return broadcast(goAsync, done, input, n)
+}
+
+func broadcast[T any](
+	spawn spawner,
+	done <-chan struct{},
+	input <-chan T,
+	n int,
 ) (outputs []<-chan T) {
 	outChans := make([]chan T, n)
 	for i := 0; i < n; i++ {
@@ -21,7 +30,7 @@ func Broadcast[T any](
 		outputs = append(outputs, outChans[i])
 	}
 
-	broadcast := func() {
+	broadcastItems := func() {
 		defer func() {
 			for _, outChan := range outChans {
 				close(outChan)
@@ -29,21 +38,22 @@ func Broadcast[T any](
 		}()
 
 		wg := sync.WaitGroup{}
-		for item := range OrDone(done, input) {
+		for item := range orDone(spawn, done, input) {
 			wg.Add(len(outChans))
 			for _, outChan := range outChans {
-				go func(item T, outChan chan T) {
+				item, outChan := item, outChan
+				spawn(func() {
 					defer wg.Done()
 					select {
 					case outChan <- item:
 					case <-done:
 					}
-				}(item, outChan)
+				})
 			}
 			wg.Wait()
 		}
 	}
-	go broadcast()
+	spawn(broadcastItems)
 
 	return
 }
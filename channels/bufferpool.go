@@ -0,0 +1,89 @@
+// Copyright 2022 Jesse Waite
+
+// bufferpool.go is for pools of byte buffers of varying sizes, built on free-lists.
+
+package channerics
+
+import (
+	"errors"
+	"math/bits"
+)
+
+var ErrInvalidSizeClasses = errors.New("size classes must be powers of two, with min <= max")
+
+// BufferPool pools byte buffers in power-of-two size classes, each of which is a FreeList.
+// Buffers larger than the largest class are neither pooled nor retained.
+type BufferPool struct {
+	// minShift is log2 of the smallest class size.
+	minShift int
+	maxSize  int
+	classes  []FreeList[[]byte]
+}
+
+// NewBufferPool returns a BufferPool with a size class for each power of two from minSize
+// to maxSize, each retaining up to perClass buffers. NewBufferPool returns
+// ErrInvalidSizeClasses if minSize or maxSize are not powers of two or minSize > maxSize,
+// and ErrInvalidSize if perClass is not positive.
+func NewBufferPool(minSize, maxSize, perClass int) (*BufferPool, error) {
+	if !isPowerOfTwo(minSize) || !isPowerOfTwo(maxSize) || minSize > maxSize {
+		return nil, ErrInvalidSizeClasses
+	}
+	if perClass <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	bp := &BufferPool{
+		minShift: bits.TrailingZeros(uint(minSize)),
+		maxSize:  maxSize,
+	}
+	for size := minSize; size <= maxSize; size *= 2 {
+		classSize := size
+		fl, err := NewFreeList(perClass, func() []byte {
+			return make([]byte, classSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+		bp.classes = append(bp.classes, fl)
+	}
+
+	return bp, nil
+}
+
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// Get returns a buffer of length n, whose capacity is that of the smallest class of at
+// least n bytes. The contents of a reused buffer are not cleared. If n exceeds the largest
+// class, Get allocates a buffer of exactly n bytes.
+func (bp *BufferPool) Get(n int) []byte {
+	if n > bp.maxSize {
+		return make([]byte, n)
+	}
+
+	// The class is log2 of n rounded up to a power of two, relative to the smallest class.
+	class := bits.Len(uint(n-1)) - bp.minShift
+	if n <= 1 || class < 0 {
+		class = 0
+	}
+	buf, _ := bp.classes[class].Get()
+	return buf[:n]
+}
+
+// Put returns a buffer to the class of the largest size not exceeding its capacity, and
+// returns true. Put returns false, not retaining the buffer, if its capacity is smaller
+// than the smallest class or larger than the largest, or if its class is full.
+func (bp *BufferPool) Put(buf []byte) bool {
+	c := cap(buf)
+	if c > bp.maxSize {
+		return false
+	}
+
+	// The class is log2 of the capacity rounded down to a power of two.
+	class := bits.Len(uint(c)) - 1 - bp.minShift
+	if class < 0 {
+		return false
+	}
+	return bp.classes[class].Put(buf[:c])
+}
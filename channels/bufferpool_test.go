@@ -0,0 +1,90 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBufferPool(t *testing.T) {
+	Convey("BufferPool tests", t, func() {
+		Convey("When we try to init a BufferPool with invalid sizes", func() {
+			for _, sizes := range [][3]int{
+				{0, 8, 1},
+				{3, 8, 1},
+				{8, 12, 1},
+				{16, 8, 1},
+			} {
+				bp, err := NewBufferPool(sizes[0], sizes[1], sizes[2])
+				So(bp, ShouldBeNil)
+				So(err, ShouldBeError, ErrInvalidSizeClasses)
+			}
+
+			bp, err := NewBufferPool(8, 16, 0)
+			So(bp, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+		})
+
+		Convey("When buffers are requested they are rounded up to their size class", func() {
+			bp, err := NewBufferPool(8, 64, 2)
+			So(err, ShouldBeNil)
+
+			for _, tc := range []struct{ n, cap int }{
+				{0, 8},
+				{1, 8},
+				{8, 8},
+				{9, 16},
+				{33, 64},
+				{64, 64},
+			} {
+				buf := bp.Get(tc.n)
+				So(len(buf), ShouldEqual, tc.n)
+				So(cap(buf), ShouldEqual, tc.cap)
+			}
+		})
+
+		Convey("When buffers are returned they are reused", func() {
+			bp, err := NewBufferPool(8, 64, 2)
+			So(err, ShouldBeNil)
+
+			buf := bp.Get(20)
+			buf[0] = 42
+			So(bp.Put(buf), ShouldBeTrue)
+
+			reused := bp.Get(17)
+			So(len(reused), ShouldEqual, 17)
+			So(reused[0], ShouldEqual, 42)
+			So(&reused[0], ShouldEqual, &buf[0])
+		})
+
+		Convey("When a buffer's capacity is between classes it is returned to the smaller class", func() {
+			bp, err := NewBufferPool(8, 64, 2)
+			So(err, ShouldBeNil)
+
+			buf := make([]byte, 3, 24)
+			So(bp.Put(buf), ShouldBeTrue)
+			reused := bp.Get(16)
+			So(&reused[0], ShouldEqual, &buf[0])
+		})
+
+		Convey("When buffers exceed the largest class they are not retained", func() {
+			bp, err := NewBufferPool(8, 64, 2)
+			So(err, ShouldBeNil)
+
+			buf := bp.Get(65)
+			So(len(buf), ShouldEqual, 65)
+			So(cap(buf), ShouldEqual, 65)
+			So(bp.Put(buf), ShouldBeFalse)
+			So(bp.Put(make([]byte, 4)), ShouldBeFalse)
+		})
+
+		Convey("When a class is full buffers are not retained", func() {
+			bp, err := NewBufferPool(8, 8, 1)
+			So(err, ShouldBeNil)
+			So(bp.Put(make([]byte, 8)), ShouldBeTrue)
+			So(bp.Put(make([]byte, 8)), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Jesse Waite
+
+// Package chanctx provides context-first variants of the channerics combinators, for code
+// whose cancellation is carried by a context.Context rather than a done channel.
+//
+// Each function returns, alongside its output channels, an err func reporting why the
+// outputs closed: nil if its inputs were exhausted, or context.Cause(ctx) if ctx was
+// cancelled first, e.g. the cause passed to a context.CancelCauseFunc.
+// The err func should be called once the outputs have closed; until then it returns nil.
+// Each output is relayed through a goroutine that records the cause of its closure, which
+// adds a little latency per value compared to the done-based combinators.
+package chanctx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/niceyeti/channerics/channels"
+)
+
+// cause records why a combinator's relays stopped. Its stop channel is the done channel
+// of the upstream combinator, which is closed only once a relay has recorded ctx's cause,
+// such that an upstream which stops can be told apart from one whose inputs were exhausted.
+type cause struct {
+	ctx  context.Context
+	stop chan struct{}
+	once sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func newCause(ctx context.Context) *cause {
+	return &cause{
+		ctx:  ctx,
+		stop: make(chan struct{}),
+	}
+}
+
+// cancel records context.Cause(ctx) and then stops the upstream combinator.
+func (c *cause) cancel() {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.err = context.Cause(c.ctx)
+		c.mu.Unlock()
+		close(c.stop)
+	})
+}
+
+func (c *cause) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// relay forwards the values of in until in closes or ctx is cancelled. Upon cancellation
+// it cancels c, whereas if in closes first nothing is recorded.
+func relay[T any](
+	ctx context.Context,
+	in <-chan T,
+	c *cause,
+) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+		for {
+			// Done-guard: cancellation has precedence over available values.
+			select {
+			case <-ctx.Done():
+				c.cancel()
+				return
+			default:
+			}
+
+			select {
+			case v, ok := <-in:
+				if !ok {
+					// Upstream only stops early once c is cancelled, which then recorded the cause.
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					c.cancel()
+					return
+				}
+			case <-ctx.Done():
+				c.cancel()
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// relayAll relays each of ins, recording causes in c. Without any relays, c is cancelled
+// once ctx is, such that the upstream combinator still stops.
+func relayAll[T any](
+	ctx context.Context,
+	c *cause,
+	ins []<-chan T,
+) []<-chan T {
+	if len(ins) == 0 {
+		go func() {
+			<-ctx.Done()
+			c.cancel()
+		}()
+	}
+
+	outs := make([]<-chan T, len(ins))
+	for i, in := range ins {
+		outs[i] = relay(ctx, in, c)
+	}
+	return outs
+}
+
+// OrDone streams values from vals until ctx is cancelled or vals is closed.
+// See channerics.OrDone.
+func OrDone[T any](
+	ctx context.Context,
+	vals <-chan T,
+) (<-chan T, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, vals, c), c.Err
+}
+
+// Merge merges multiple channels into a single output channel.
+// See channerics.Merge.
+func Merge[T any](
+	ctx context.Context,
+	inputs ...<-chan T,
+) (<-chan T, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, channerics.Merge(c.stop, inputs...), c), c.Err
+}
+
+// Broadcast returns n channels that repeat the data of the input channel.
+// See channerics.Broadcast.
+func Broadcast[T any](
+	ctx context.Context,
+	input <-chan T,
+	n int,
+) ([]<-chan T, func() error) {
+	c := newCause(ctx)
+	return relayAll(ctx, c, channerics.Broadcast(c.stop, input, n)), c.Err
+}
+
+// Tee streams input values to both returned output channels.
+// See channerics.Tee.
+func Tee[T any](
+	ctx context.Context,
+	in <-chan T,
+) (<-chan T, <-chan T, func() error) {
+	c := newCause(ctx)
+	out1, out2 := channerics.Tee(c.stop, in)
+	outs := relayAll(ctx, c, []<-chan T{out1, out2})
+	return outs[0], outs[1], c.Err
+}
+
+// Generator streams values via the passed generator until it returns false.
+// See channerics.Generator.
+func Generator[T any](
+	ctx context.Context,
+	generate func() (T, bool),
+) (<-chan T, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, channerics.Generator(c.stop, generate), c), c.Err
+}
+
+// Repeater loops over the passed slice until ctx is cancelled, so its err func
+// always reports the cancellation once the output closes.
+// See channerics.Repeater.
+func Repeater[T any](
+	ctx context.Context,
+	seq []T,
+) (<-chan T, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, channerics.Repeater(c.stop, seq), c), c.Err
+}
+
+// Convert returns a channel of vals converted using convertFn.
+// See channerics.Convert.
+func Convert[T1 any, T2 any](
+	ctx context.Context,
+	vals <-chan T1,
+	convertFn func(T1) T2,
+) (<-chan T2, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, channerics.Convert(c.stop, vals, convertFn), c), c.Err
+}
+
+// AsType takes a channel of interfaces and converts it to a specific type.
+// See channerics.AsType.
+func AsType[T any](
+	ctx context.Context,
+	vals chan interface{},
+) (<-chan T, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, channerics.AsType[T](c.stop, vals), c), c.Err
+}
+
+// NewTicker returns a channel of ticks from clock until ctx is cancelled, so its err func
+// always reports the cancellation once the output closes.
+// See channerics.NewTicker.
+func NewTicker(
+	ctx context.Context,
+	clock channerics.Clock,
+	duration time.Duration,
+) (<-chan time.Time, func() error) {
+	c := newCause(ctx)
+	return relay(ctx, channerics.NewTickerWithClock(c.stop, clock, duration), c), c.Err
+}
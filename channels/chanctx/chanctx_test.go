@@ -0,0 +1,195 @@
+// Copyright 2022 Jesse Waite
+
+package chanctx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/niceyeti/channerics/channels"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChanctx(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	// sliceChan returns a closed channel buffering vals.
+	sliceChan := func(vals ...int) <-chan int {
+		ch := make(chan int, len(vals))
+		for _, v := range vals {
+			ch <- v
+		}
+		close(ch)
+		return ch
+	}
+
+	// collect reads ch until it closes.
+	collect := func(ch <-chan int) (vals []int) {
+		timeout := time.After(maxWaitForEffect)
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return
+				}
+				vals = append(vals, v)
+			case <-timeout:
+				t.FailNow()
+			}
+		}
+	}
+
+	// awaitClosed drains ch until it closes.
+	awaitClosed := func(ch <-chan int) {
+		collect(ch)
+	}
+
+	Convey("chanctx tests", t, func() {
+		Convey("When inputs are exhausted err is nil, even if ctx is cancelled afterward", func() {
+			ctx, cancelFn := context.WithCancel(context.Background())
+			out, errFn := OrDone(ctx, sliceChan(1, 2, 3))
+			So(collect(out), ShouldResemble, []int{1, 2, 3})
+			cancelFn()
+			So(errFn(), ShouldBeNil)
+		})
+
+		Convey("When ctx is cancelled err reports the cause", func() {
+			ctx, cancelFn := context.WithCancel(context.Background())
+			out, errFn := OrDone(ctx, make(chan int))
+			So(errFn(), ShouldBeNil)
+			cancelFn()
+			awaitClosed(out)
+			So(errFn(), ShouldBeError, context.Canceled)
+		})
+
+		Convey("When ctx is cancelled with a cause err reports the cause", func() {
+			errCause := errors.New("shutting down")
+			ctx, cancelFn := context.WithCancelCause(context.Background())
+			out1, out2, errFn := Tee(ctx, make(chan int))
+			cancelFn(errCause)
+			awaitClosed(out1)
+			awaitClosed(out2)
+			So(errors.Is(errFn(), errCause), ShouldBeTrue)
+		})
+
+		Convey("When ctx is cancelled while a value awaits its consumer err reports the cause", func() {
+			errCause := errors.New("consumer stalled")
+			ctx, cancelFn := context.WithCancelCause(context.Background())
+			out, errFn := OrDone(ctx, sliceChan(1))
+
+			// Let the relay block on sending 1, then cancel instead of reading it.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			cancelFn(errCause)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			So(collect(out), ShouldBeEmpty)
+			So(errors.Is(errFn(), errCause), ShouldBeTrue)
+		})
+
+		Convey("When upstream inputs are exhausted before ctx is cancelled err is nil", func() {
+			ctx, cancelFn := context.WithCancel(context.Background())
+			outs, errFn := Broadcast(ctx, sliceChan(1), 1)
+			So(collect(outs[0]), ShouldResemble, []int{1})
+			cancelFn()
+			So(errFn(), ShouldBeNil)
+		})
+
+		Convey("When Broadcast has no outputs cancelling ctx stops it", func() {
+			ctx, cancelFn := context.WithCancel(context.Background())
+			outs, errFn := Broadcast(ctx, make(chan int), 0)
+			So(outs, ShouldBeEmpty)
+			cancelFn()
+			deadline := time.After(maxWaitForEffect)
+			for errFn() == nil {
+				select {
+				case <-deadline:
+					t.FailNow()
+				case <-time.After(time.Millisecond):
+				}
+			}
+			So(errFn(), ShouldBeError, context.Canceled)
+		})
+
+		Convey("When ctx is already cancelled no values are output", func() {
+			ctx, cancelFn := context.WithCancel(context.Background())
+			cancelFn()
+			out, errFn := Merge(ctx, sliceChan(1, 2, 3), sliceChan(4))
+			So(collect(out), ShouldBeEmpty)
+			So(errFn(), ShouldBeError, context.Canceled)
+		})
+
+		Convey("When ctx times out err reports the deadline", func() {
+			ctx, cancelFn := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+			defer cancelFn()
+			out, errFn := Repeater(ctx, []int{1})
+			for range out {
+			}
+			So(errFn(), ShouldBeError, context.DeadlineExceeded)
+		})
+
+		Convey("When Merge inputs are exhausted", func() {
+			out, errFn := Merge(context.Background(), sliceChan(1, 2), sliceChan(3))
+			So(collect(out), ShouldHaveLength, 3)
+			So(errFn(), ShouldBeNil)
+		})
+
+		Convey("When Broadcast and Tee outputs are read", func() {
+			outs, errFn := Broadcast(context.Background(), sliceChan(1, 2), 2)
+			So(outs, ShouldHaveLength, 2)
+			done := make(chan []int)
+			go func() { done <- collect(outs[1]) }()
+			So(collect(outs[0]), ShouldResemble, []int{1, 2})
+			So(<-done, ShouldResemble, []int{1, 2})
+			So(errFn(), ShouldBeNil)
+
+			ctx, cancelFn := context.WithCancel(context.Background())
+			out1, out2, errFn := Tee(ctx, make(chan int))
+			cancelFn()
+			awaitClosed(out1)
+			awaitClosed(out2)
+			So(errFn(), ShouldBeError, context.Canceled)
+		})
+
+		Convey("When Generator, Convert and AsType inputs are exhausted", func() {
+			ctx := context.Background()
+			i := 0
+			gen, errFn := Generator(ctx, func() (int, bool) {
+				i++
+				return i, i <= 3
+			})
+			doubled, convertErrFn := Convert(ctx, gen, func(v int) int { return v * 2 })
+			So(collect(doubled), ShouldResemble, []int{2, 4, 6})
+			So(errFn(), ShouldBeNil)
+			So(convertErrFn(), ShouldBeNil)
+
+			vals := make(chan interface{}, 2)
+			vals <- 7
+			vals <- 8
+			close(vals)
+			typed, errFn := AsType[int](ctx, vals)
+			So(collect(typed), ShouldResemble, []int{7, 8})
+			So(errFn(), ShouldBeNil)
+		})
+
+		Convey("When NewTicker's ctx is cancelled", func() {
+			clock := channerics.NewFakeClock(time.Unix(0, 0))
+			ctx, cancelFn := context.WithCancel(context.Background())
+			ticks, errFn := NewTicker(ctx, clock, time.Second)
+
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			select {
+			case tick := <-ticks:
+				So(tick, ShouldEqual, time.Unix(1, 0))
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			cancelFn()
+			for range ticks {
+			}
+			So(errFn(), ShouldBeError, context.Canceled)
+		})
+	})
+}
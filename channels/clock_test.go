@@ -0,0 +1,135 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFakeClock(t *testing.T) {
+	epoch := time.Unix(0, 0)
+
+	Convey("FakeClock tests", t, func() {
+		Convey("When the clock is advanced Now moves forward", func() {
+			clock := NewFakeClock(epoch)
+			So(clock.Now(), ShouldEqual, epoch)
+			clock.Advance(time.Second)
+			So(clock.Now(), ShouldEqual, epoch.Add(time.Second))
+		})
+
+		Convey("When a timer comes due it fires once with its due time", func() {
+			clock := NewFakeClock(epoch)
+			timer := clock.NewTimer(time.Second)
+
+			clock.Advance(time.Duration(999) * time.Millisecond)
+			select {
+			case <-timer.C():
+				t.FailNow()
+			default:
+			}
+
+			clock.Advance(time.Hour)
+			So(<-timer.C(), ShouldEqual, epoch.Add(time.Second))
+			So(timer.Stop(), ShouldBeFalse)
+		})
+
+		Convey("When a timer is stopped and reset", func() {
+			clock := NewFakeClock(epoch)
+			timer := clock.NewTimer(time.Second)
+			So(timer.Stop(), ShouldBeTrue)
+
+			clock.Advance(time.Hour)
+			select {
+			case <-timer.C():
+				t.FailNow()
+			default:
+			}
+
+			So(timer.Reset(time.Second), ShouldBeFalse)
+			So(timer.Reset(time.Minute), ShouldBeTrue)
+			clock.Advance(time.Minute)
+			So(<-timer.C(), ShouldEqual, epoch.Add(time.Hour+time.Minute))
+		})
+
+		Convey("When a non-positive duration is passed the timer fires immediately", func() {
+			clock := NewFakeClock(epoch)
+			So(<-clock.After(0), ShouldEqual, epoch)
+
+			timer := clock.NewTimer(time.Second)
+			timer.Reset(0)
+			So(<-timer.C(), ShouldEqual, epoch)
+		})
+
+		Convey("When a ticker's receiver falls behind ticks are dropped", func() {
+			clock := NewFakeClock(epoch)
+			ticker := clock.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			clock.Advance(time.Duration(3) * time.Second)
+			So(<-ticker.C(), ShouldEqual, epoch.Add(time.Second))
+			select {
+			case <-ticker.C():
+				t.FailNow()
+			default:
+			}
+
+			clock.Advance(time.Second)
+			So(<-ticker.C(), ShouldEqual, epoch.Add(time.Duration(4)*time.Second))
+		})
+
+		Convey("When a non-positive ticker interval is passed", func() {
+			clock := NewFakeClock(epoch)
+			So(func() { clock.NewTicker(0) }, ShouldPanic)
+		})
+
+		Convey("When BlockUntil awaits a timer created by another goroutine", func() {
+			clock := NewFakeClock(epoch)
+			fired := make(chan time.Time)
+			go func() {
+				fired <- <-clock.After(time.Second)
+			}()
+
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			So(<-fired, ShouldEqual, epoch.Add(time.Second))
+		})
+	})
+}
+
+func TestSystemClock(t *testing.T) {
+	Convey("SystemClock tests -- for coverage", t, func() {
+		var clock Clock = SystemClock{}
+		So(clock.Now().IsZero(), ShouldBeFalse)
+
+		ticker := clock.NewTicker(time.Millisecond)
+		<-ticker.C()
+		ticker.Stop()
+
+		timer := clock.NewTimer(time.Hour)
+		So(timer.Stop(), ShouldBeTrue)
+		So(timer.Reset(time.Millisecond), ShouldBeFalse)
+		<-timer.C()
+
+		<-clock.After(time.Millisecond)
+	})
+}
+
+// advanceUntil advances clock by step until a value is received from ch, or ch is closed.
+// This is needed when the goroutine under test arms its timer asynchronously, such that an
+// advance may occur before the timer exists; the received values remain deterministic.
+func advanceUntil[T any](t *testing.T, clock *FakeClock, step time.Duration, ch <-chan T) (v T, ok bool) {
+	for i := 0; i < 1000; i++ {
+		select {
+		case v, ok = <-ch:
+			return
+		case <-time.After(time.Millisecond):
+		}
+		clock.Advance(step)
+	}
+
+	t.FailNow()
+	return
+}
@@ -5,16 +5,25 @@
 package channerics
 
 // OrDone streams values from vals until done or vals is closed.
-// OrDone is the counterpart to Send which does the same for a writable channel.
+// For single operations with the same precedence, see Recv and its counterpart Send,
+// which does the same for a writable channel.
 // Note the done-guard below, which is predominately for buffered channels;
 // a done-guard ensures that done's closure has higher precedence than the vals channel.
 func OrDone[T any](
 	done <-chan struct{},
 	vals <-chan T,
+) <-chan T {
+	return orDone(goAsync, done, vals)
+}
+
+func orDone[T any](
+	spawn spawner,
+	done <-chan struct{},
+	vals <-chan T,
 ) <-chan T {
 	output := make(chan T)
 
-	go func() {
+	spawn(func() {
 		defer close(output)
 		for {
 			// Done-guard: this check's 'done' with higher precedence, whereas the
@@ -40,7 +49,7 @@ func OrDone[T any](
 				return
 			}
 		}
-	}()
+	})
 
 	return output
 }
@@ -59,7 +68,7 @@ func Any[T any](
 	case 1:
 		return chans[0]
 	case 2:
-		return eitherDone(chans[0], chans[1])
+		return eitherDone(goAsync, chans[0], chans[1])
 	}
 
 	done := make(chan T)
@@ -77,17 +86,18 @@ func Any[T any](
 }
 
 func eitherDone[T any](
+	spawn spawner,
 	ch1, ch2 <-chan T,
 ) <-chan T {
 	done := make(chan T)
 
-	go func() {
+	spawn(func() {
 		defer close(done)
 		select {
 		case <-ch1:
 		case <-ch2:
 		}
-	}()
+	})
 
 	return done
 }
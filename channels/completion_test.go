@@ -198,7 +198,7 @@ func TestAny(t *testing.T) {
 			Convey("When first channel is closed first", func() {
 				ch1 := make(chan struct{})
 				ch2 := make(chan struct{})
-				done := eitherDone(ch1, ch2)
+				done := eitherDone(goAsync, ch1, ch2)
 
 				close(ch1)
 				exitedViaDone := false
@@ -213,7 +213,7 @@ func TestAny(t *testing.T) {
 			Convey("When second channel is closed first", func() {
 				ch1 := make(chan struct{})
 				ch2 := make(chan struct{})
-				done := eitherDone(ch1, ch2)
+				done := eitherDone(goAsync, ch1, ch2)
 
 				close(ch2)
 				exitedViaDone := false
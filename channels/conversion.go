@@ -9,18 +9,26 @@ package channerics
 func AsType[T any](
 	done <-chan struct{},
 	vals chan interface{},
+) <-chan T {
+	return asType[T](goAsync, done, vals)
+}
+
+func asType[T any](
+	spawn spawner,
+	done <-chan struct{},
+	vals chan interface{},
 ) <-chan T {
 	ch := make(chan T)
 
-	go func() {
+	spawn(func() {
 		defer close(ch)
-		for v := range OrDone(done, vals) {
+		for v := range orDone(spawn, done, vals) {
 			select {
 			case ch <- v.(T):
 			case <-done:
 			}
 		}
-	}()
+	})
 
 	return ch
 }
@@ -31,19 +39,28 @@ func Convert[T1 any, T2 any](
 	done <-chan struct{},
 	vals <-chan T1,
 	convertFn func(T1) T2,
+) <-chan T2 {
+	return convert(goAsync, done, vals, convertFn)
+}
+
+func convert[T1 any, T2 any](
+	spawn spawner,
+	done <-chan struct{},
+	vals <-chan T1,
+	convertFn func(T1) T2,
 ) <-chan T2 {
 	out := make(chan T2)
 
-	go func() {
+	spawn(func() {
 		defer close(out)
 
-		for val := range OrDone(done, vals) {
+		for val := range orDone(spawn, done, vals) {
 			select {
 			case out <- convertFn(val):
 			case <-done:
 			}
 		}
-	}()
+	})
 
 	return out
 }
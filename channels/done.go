@@ -0,0 +1,134 @@
+// Copyright 2022 Jesse Waite
+
+// done.go is for done channels which carry the cause of their closure, such that callers
+// can tell a shutdown from a timeout or failure once a pipeline exits early.
+
+package channerics
+
+import (
+	"context"
+	"sync"
+)
+
+// Done pairs a done channel, closed once, with the error that caused its closure.
+// Pass Done() wherever a done channel is accepted, and check Err() after the function
+// observing it exits. Children derived via Child are cancelled with their parent's cause.
+// The zero value is not usable; use NewDone.
+type Done struct {
+	ch chan struct{}
+
+	mu       sync.Mutex
+	err      error
+	parent   *Done
+	children map[*Done]struct{}
+}
+
+// NewDone returns a Done which is not cancelled.
+func NewDone() *Done {
+	return &Done{
+		ch: make(chan struct{}),
+	}
+}
+
+// Done returns the done channel, which is closed when d is cancelled.
+func (d *Done) Done() <-chan struct{} {
+	return d.ch
+}
+
+// Err returns nil if d is not cancelled, and otherwise the cause with which it was cancelled.
+func (d *Done) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Cancel closes the done channel, recording err as its cause, and cancels d's children
+// with the same cause. As with context.CancelCauseFunc a nil err is recorded as
+// context.Canceled. Calls after the first have no effect.
+func (d *Done) Cancel(err error) {
+	if err == nil {
+		err = context.Canceled
+	}
+
+	d.mu.Lock()
+	if d.err != nil {
+		d.mu.Unlock()
+		return
+	}
+	d.err = err
+	close(d.ch)
+	children := d.children
+	d.children = nil
+	parent := d.parent
+	d.parent = nil
+	d.mu.Unlock()
+
+	for child := range children {
+		child.Cancel(err)
+	}
+	if parent != nil {
+		parent.removeChild(d)
+	}
+}
+
+// Child returns a Done which is cancelled when d is, with d's cause, but which may also be
+// cancelled independently without affecting d. A child of a cancelled Done is cancelled.
+func (d *Done) Child() *Done {
+	child := NewDone()
+
+	d.mu.Lock()
+	if d.err != nil {
+		err := d.err
+		d.mu.Unlock()
+		child.Cancel(err)
+		return child
+	}
+	if d.children == nil {
+		d.children = make(map[*Done]struct{})
+	}
+	d.children[child] = struct{}{}
+	child.parent = d
+	d.mu.Unlock()
+
+	return child
+}
+
+func (d *Done) removeChild(child *Done) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.children, child)
+}
+
+// Context returns a context derived from parent which is also cancelled when d is,
+// such that context.Cause reports d's cause. The returned cancel func releases the
+// context's resources and should be called once it is no longer needed.
+func (d *Done) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancelFn := context.WithCancelCause(parent)
+
+	go func() {
+		select {
+		case <-d.ch:
+			cancelFn(d.Err())
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() { cancelFn(nil) }
+}
+
+// DoneFromContext returns a Done which is cancelled with context.Cause(ctx) when ctx is done,
+// e.g. the cause passed to a context.CancelCauseFunc. The returned Done must eventually be
+// cancelled, by ctx or by calling Cancel, to release its resources.
+func DoneFromContext(ctx context.Context) *Done {
+	d := NewDone()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			d.Cancel(context.Cause(ctx))
+		case <-d.ch:
+		}
+	}()
+
+	return d
+}
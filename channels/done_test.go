@@ -0,0 +1,114 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDone(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	errShutdown := errors.New("shutdown")
+
+	isClosed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		case <-time.After(maxWaitForEffect):
+			return false
+		}
+	}
+
+	Convey("Done tests", t, func() {
+		Convey("When cancelled the first cause is kept", func() {
+			d := NewDone()
+			So(d.Err(), ShouldBeNil)
+			select {
+			case <-d.Done():
+				t.FailNow()
+			default:
+			}
+
+			d.Cancel(errShutdown)
+			d.Cancel(errors.New("ignored"))
+			So(isClosed(d.Done()), ShouldBeTrue)
+			So(d.Err(), ShouldEqual, errShutdown)
+		})
+
+		Convey("When cancelled with a nil cause", func() {
+			d := NewDone()
+			d.Cancel(nil)
+			So(d.Err(), ShouldEqual, context.Canceled)
+		})
+
+		Convey("When a parent is cancelled its children are cancelled with its cause", func() {
+			parent := NewDone()
+			child := parent.Child()
+			grandchild := child.Child()
+
+			parent.Cancel(errShutdown)
+			So(isClosed(grandchild.Done()), ShouldBeTrue)
+			So(child.Err(), ShouldEqual, errShutdown)
+			So(grandchild.Err(), ShouldEqual, errShutdown)
+
+			late := parent.Child()
+			So(late.Err(), ShouldEqual, errShutdown)
+		})
+
+		Convey("When a child is cancelled its parent is not", func() {
+			parent := NewDone()
+			child := parent.Child()
+			child.Cancel(errShutdown)
+
+			So(parent.Err(), ShouldBeNil)
+			So(parent.children, ShouldBeEmpty)
+		})
+
+		Convey("When passed as a done channel the cause explains early exit", func() {
+			d := NewDone()
+			out := Merge(d.Done(), make(chan int), make(chan int))
+			d.Cancel(context.DeadlineExceeded)
+
+			select {
+			case _, ok := <-out:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(d.Err(), ShouldBeError, context.DeadlineExceeded)
+		})
+
+		Convey("When converted to a context its cause is reported by context.Cause", func() {
+			d := NewDone()
+			ctx, cancelFn := d.Context(context.Background())
+			defer cancelFn()
+
+			d.Cancel(errShutdown)
+			So(isClosed(ctx.Done()), ShouldBeTrue)
+			So(ctx.Err(), ShouldEqual, context.Canceled)
+			So(context.Cause(ctx), ShouldEqual, errShutdown)
+		})
+
+		Convey("When a converted context is cancelled d is not", func() {
+			d := NewDone()
+			ctx, cancelFn := d.Context(context.Background())
+			cancelFn()
+			So(isClosed(ctx.Done()), ShouldBeTrue)
+			So(d.Err(), ShouldBeNil)
+		})
+
+		Convey("When derived from a context the context's cause is kept", func() {
+			ctx, cancelFn := context.WithCancelCause(context.Background())
+			d := DoneFromContext(ctx)
+			cancelFn(errShutdown)
+
+			So(isClosed(d.Done()), ShouldBeTrue)
+			So(d.Err(), ShouldEqual, errShutdown)
+		})
+	})
+}
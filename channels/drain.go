@@ -0,0 +1,107 @@
+// Copyright 2022 Jesse Waite
+
+// drain.go is for graceful variants of the combinators, which upon a 'stop accepting' signal
+// flush the items already accepted upstream instead of dropping them, e.g. during a rolling
+// restart. The stop signal is typically also the one that tells producers to stop producing,
+// such that the inputs soon close.
+
+package channerics
+
+import "time"
+
+// OrDoneDrain is OrDone in drain-on-done mode: once stop is closed it continues streaming
+// values from vals until vals is closed, or until drainTimeout has elapsed, whichever is first.
+// A non-positive drainTimeout drains until vals is closed. Closing done still stops the stage
+// immediately, with precedence over the remaining values.
+func OrDoneDrain[T any](
+	done <-chan struct{},
+	stop <-chan struct{},
+	clock Clock,
+	drainTimeout time.Duration,
+	vals <-chan T,
+) <-chan T {
+	return withDrain(done, stop, clock, drainTimeout, func(spawn spawner, done <-chan struct{}) <-chan T {
+		return orDone(spawn, done, vals)
+	})
+}
+
+// MergeDrain is Merge in drain-on-done mode: once stop is closed it continues merging until
+// all inputs are closed, or until drainTimeout has elapsed. See OrDoneDrain.
+func MergeDrain[T any](
+	done <-chan struct{},
+	stop <-chan struct{},
+	clock Clock,
+	drainTimeout time.Duration,
+	inputs ...<-chan T,
+) <-chan T {
+	return withDrain(done, stop, clock, drainTimeout, func(spawn spawner, done <-chan struct{}) <-chan T {
+		return merge(spawn, done, inputs...)
+	})
+}
+
+// ConvertDrain is Convert in drain-on-done mode: once stop is closed it continues converting
+// until vals is closed, or until drainTimeout has elapsed. See OrDoneDrain.
+func ConvertDrain[T1 any, T2 any](
+	done <-chan struct{},
+	stop <-chan struct{},
+	clock Clock,
+	drainTimeout time.Duration,
+	vals <-chan T1,
+	convertFn func(T1) T2,
+) <-chan T2 {
+	return withDrain(done, stop, clock, drainTimeout, func(spawn spawner, done <-chan struct{}) <-chan T2 {
+		return convert(spawn, done, vals, convertFn)
+	})
+}
+
+// withDrain starts a combinator with a done channel which closes when done is closed, or once
+// drainTimeout has elapsed after stop is closed. The goroutine managing the deadline exits
+// along with the combinator's goroutines.
+func withDrain[T any](
+	done <-chan struct{},
+	stop <-chan struct{},
+	clock Clock,
+	drainTimeout time.Duration,
+	start func(spawn spawner, done <-chan struct{}) <-chan T,
+) <-chan T {
+	deadline := make(chan struct{})
+
+	// Done-guard: if done is already closed, the combinator must not observe it late.
+	select {
+	case <-done:
+		close(deadline)
+		return start(goAsync, deadline)
+	default:
+	}
+
+	j := &joiner{}
+	out := start(j.spawn, deadline)
+	exited := j.exited()
+
+	go func() {
+		defer close(deadline)
+
+		select {
+		case <-done:
+			return
+		case <-exited:
+			return
+		case <-stop:
+		}
+
+		var expired <-chan time.Time
+		if drainTimeout > 0 {
+			timer := clock.NewTimer(drainTimeout)
+			defer timer.Stop()
+			expired = timer.C()
+		}
+
+		select {
+		case <-done:
+		case <-exited:
+		case <-expired:
+		}
+	}()
+
+	return out
+}
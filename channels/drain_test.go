@@ -0,0 +1,125 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDrain(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	// bufferedChan returns a channel buffering vals, which is closed if closed is true.
+	bufferedChan := func(closed bool, vals ...int) chan int {
+		ch := make(chan int, len(vals))
+		for _, v := range vals {
+			ch <- v
+		}
+		if closed {
+			close(ch)
+		}
+		return ch
+	}
+
+	// collect reads ch until it closes.
+	collect := func(ch <-chan int) (vals []int) {
+		timeout := time.After(maxWaitForEffect)
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return
+				}
+				vals = append(vals, v)
+			case <-timeout:
+				t.FailNow()
+			}
+		}
+	}
+
+	Convey("Drain tests", t, func() {
+		clock := NewFakeClock(epoch)
+		done := make(chan struct{})
+		stop := make(chan struct{})
+
+		Convey("When stop is closed accepted values are flushed until the input closes", func() {
+			in := bufferedChan(false, 1, 2, 3)
+			out := OrDoneDrain(done, stop, clock, time.Minute, in)
+			close(stop)
+			// Await the drain deadline, such that the values are read after stop.
+			clock.BlockUntil(1)
+			close(in)
+
+			So(collect(out), ShouldResemble, []int{1, 2, 3})
+		})
+
+		Convey("When the input does not close the drain deadline closes the output", func() {
+			in := bufferedChan(false, 1)
+			out := OrDoneDrain(done, stop, clock, time.Minute, in)
+			close(stop)
+			clock.BlockUntil(1)
+			So(<-out, ShouldEqual, 1)
+
+			clock.Advance(time.Minute)
+			So(collect(out), ShouldBeEmpty)
+		})
+
+		Convey("When the drain timeout is not positive the input is drained until closed", func() {
+			in := bufferedChan(false, 1, 2)
+			out := OrDoneDrain(done, stop, clock, 0, in)
+			close(stop)
+			So(<-out, ShouldEqual, 1)
+			close(in)
+			So(collect(out), ShouldResemble, []int{2})
+		})
+
+		Convey("When done is closed values are dropped as usual", func() {
+			in := bufferedChan(true, 1, 2, 3)
+			close(done)
+			out := OrDoneDrain(done, stop, clock, time.Minute, in)
+			So(collect(out), ShouldBeEmpty)
+		})
+
+		Convey("When done is closed during the drain the output closes", func() {
+			in := bufferedChan(false)
+			out := OrDoneDrain(done, stop, clock, time.Minute, in)
+			close(stop)
+			clock.BlockUntil(1)
+			close(done)
+			So(collect(out), ShouldBeEmpty)
+		})
+
+		Convey("When the input closes without stop the stage exits", func() {
+			in := bufferedChan(true, 1)
+			out := OrDoneDrain(done, stop, clock, time.Minute, in)
+			So(collect(out), ShouldResemble, []int{1})
+		})
+
+		Convey("When MergeDrain and ConvertDrain are stopped they flush their inputs", func() {
+			in1 := bufferedChan(false, 1, 2)
+			in2 := bufferedChan(false, 3)
+			merged := MergeDrain(done, stop, clock, time.Minute, in1, in2)
+			converted := ConvertDrain(done, stop, clock, time.Minute, merged, func(i int) string {
+				return strconv.Itoa(i)
+			})
+			close(stop)
+			clock.BlockUntil(2)
+			close(in1)
+			close(in2)
+
+			var vals []string
+			for v := range converted {
+				vals = append(vals, v)
+			}
+			So(vals, ShouldHaveLength, 3)
+			So(vals, ShouldContain, "1")
+			So(vals, ShouldContain, "2")
+			So(vals, ShouldContain, "3")
+		})
+	})
+}
@@ -3,7 +3,10 @@
 
 package channerics
 
-import "errors"
+import (
+	"errors"
+	"sync/atomic"
+)
 
 // FreeList maintains a list of items for reuse as a strategy to reduce memory
 // memory allocations. It can be implemented using a simple buffered channel.
@@ -13,11 +16,73 @@ type FreeList[T any] interface {
 	Get() (T, bool)
 	// Return an item to the free-list.
 	Put(T) bool
+	// Drain removes all items from the free-list, passing each to destroy if non-nil.
+	Drain(destroy func(T))
+	// Stats returns the free-list's counters, which are zero unless counting is enabled.
+	Stats() FreeListStats
+}
+
+// FreeListOptions are the optional settings of a free-list. The zero value is valid.
+type FreeListOptions[T any] struct {
+	// Reset, if non-nil, is applied to items passed to Put, e.g. to truncate buffers.
+	Reset func(T)
+	// CountStats enables the counters returned by Stats.
+	CountStats bool
+}
+
+// FreeListStats are the aggregate counters of a free-list, for tuning its size.
+type FreeListStats struct {
+	// Hits is the number of Gets returning a free item.
+	Hits uint64
+	// Misses is the number of Gets allocating a new item.
+	Misses uint64
+	// Drops is the number of Puts discarding an item because the free-list was full.
+	Drops uint64
+}
+
+// HitRate returns the fraction of Gets that returned a free item, or zero if there were none.
+func (s FreeListStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// recordGet counts a Get, if s is non-nil.
+func (s *FreeListStats) recordGet(isNew bool) {
+	if s == nil {
+		return
+	}
+	if isNew {
+		atomic.AddUint64(&s.Misses, 1)
+	} else {
+		atomic.AddUint64(&s.Hits, 1)
+	}
+}
+
+// recordDrop counts a dropped Put, if s is non-nil.
+func (s *FreeListStats) recordDrop() {
+	if s != nil {
+		atomic.AddUint64(&s.Drops, 1)
+	}
+}
+
+// load returns a snapshot of s, or zero counters if s is nil.
+func (s *FreeListStats) load() (stats FreeListStats) {
+	if s != nil {
+		stats.Hits = atomic.LoadUint64(&s.Hits)
+		stats.Misses = atomic.LoadUint64(&s.Misses)
+		stats.Drops = atomic.LoadUint64(&s.Drops)
+	}
+	return
 }
 
 type freeList[T any] struct {
-	ch   chan T
-	newt func() T
+	ch    chan T
+	newt  func() T
+	reset func(T)
+	// stats is nil unless counting is enabled.
+	stats *FreeListStats
 }
 
 var ErrInvalidSize = errors.New("size must be greater than zero")
@@ -26,26 +91,46 @@ var ErrInvalidSize = errors.New("size must be greater than zero")
 func NewFreeList[T any](
 	size int,
 	createFn func() T,
+) (FreeList[T], error) {
+	return NewFreeListWithOptions(size, createFn, FreeListOptions[T]{})
+}
+
+// NewFreeListWithOptions returns a free list like NewFreeList, configured per opts.
+func NewFreeListWithOptions[T any](
+	size int,
+	createFn func() T,
+	opts FreeListOptions[T],
 ) (FreeList[T], error) {
 	if size <= 0 {
 		return nil, ErrInvalidSize
 	}
 
-	return &freeList[T]{
-		ch:   make(chan T, size),
-		newt: createFn,
-	}, nil
+	fl := &freeList[T]{
+		ch:    make(chan T, size),
+		newt:  createFn,
+		reset: opts.Reset,
+	}
+	if opts.CountStats {
+		fl.stats = &FreeListStats{}
+	}
+
+	return fl, nil
 }
 
 // Put returns an item to the free-list if not at capacity and returns true,
 // otherwise does not add 't' and returns false.
 func (fl *freeList[T]) Put(t T) bool {
+	if fl.reset != nil {
+		fl.reset(t)
+	}
+
 	select {
 	case fl.ch <- t:
 		return true
 	default:
 		// do nothing, drop t on the floor
 	}
+	fl.stats.recordDrop()
 	return false
 }
 
@@ -59,5 +144,31 @@ func (fl *freeList[T]) Get() (t T, isNew bool) {
 		isNew = true
 	}
 
+	fl.stats.recordGet(isNew)
 	return
 }
+
+// Drain removes the items currently in the free-list, passing each to destroy if non-nil,
+// e.g. to release pooled resources on shutdown. Items put concurrently may remain.
+func (fl *freeList[T]) Drain(destroy func(T)) {
+	drain(fl.ch, destroy)
+}
+
+// drain receives from ch until it is empty, passing each item to destroy if non-nil.
+func drain[T any](ch <-chan T, destroy func(T)) {
+	for {
+		select {
+		case t := <-ch:
+			if destroy != nil {
+				destroy(t)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the free-list's counters.
+func (fl *freeList[T]) Stats() FreeListStats {
+	return fl.stats.load()
+}
@@ -113,5 +113,81 @@ func TestFreeList(t *testing.T) {
 			So(ok, ShouldBeFalse)
 		})
 
+		Convey("When stats are counted", func() {
+			fl, err := NewFreeListWithOptions(
+				1,
+				func() []byte {
+					return make([]byte, 0, 8)
+				},
+				FreeListOptions[[]byte]{CountStats: true},
+			)
+			So(err, ShouldBeNil)
+			So(fl.Stats(), ShouldResemble, FreeListStats{})
+			So(fl.Stats().HitRate(), ShouldEqual, 0)
+
+			b1, _ := fl.Get()
+			b2, _ := fl.Get()
+			So(fl.Put(b1), ShouldBeTrue)
+			So(fl.Put(b2), ShouldBeFalse)
+			fl.Get()
+			fl.Get()
+
+			stats := fl.Stats()
+			So(stats, ShouldResemble, FreeListStats{Hits: 1, Misses: 3, Drops: 1})
+			So(stats.HitRate(), ShouldEqual, 0.25)
+		})
+
+		Convey("When stats are not counted they remain zero", func() {
+			fl, err := NewFreeList(1, func() int { return 1 })
+			So(err, ShouldBeNil)
+			fl.Get()
+			fl.Put(1)
+			fl.Put(1)
+			So(fl.Stats(), ShouldResemble, FreeListStats{})
+		})
+
+		Convey("When items are put they are reset", func() {
+			fl, err := NewFreeListWithOptions(
+				2,
+				func() []byte {
+					return make([]byte, 0, 8)
+				},
+				FreeListOptions[[]byte]{
+					Reset: func(b []byte) {
+						for i := range b {
+							b[i] = 0
+						}
+					},
+				},
+			)
+			So(err, ShouldBeNil)
+
+			b, _ := fl.Get()
+			b = append(b, 1, 2, 3)
+			fl.Put(b)
+			b, isNew := fl.Get()
+			So(isNew, ShouldBeFalse)
+			So(b, ShouldResemble, []byte{0, 0, 0})
+		})
+
+		Convey("When the free-list is drained its items are destroyed", func() {
+			fl, err := NewFreeList(3, func() int { return 0 })
+			So(err, ShouldBeNil)
+			fl.Put(1)
+			fl.Put(2)
+
+			var destroyed []int
+			fl.Drain(func(i int) {
+				destroyed = append(destroyed, i)
+			})
+			So(destroyed, ShouldResemble, []int{1, 2})
+
+			// A nil destroy func simply discards items.
+			fl.Put(3)
+			fl.Drain(nil)
+			i, isNew := fl.Get()
+			So(i, ShouldEqual, 0)
+			So(isNew, ShouldBeTrue)
+		})
 	})
 }
@@ -0,0 +1,198 @@
+// Copyright 2022 Jesse Waite
+
+// join.go is for variants of the combinators which also return a join handle, a channel
+// closed once every goroutine started by the combinator has exited. A combinator's outputs
+// may close before its goroutines exit, so the handle is needed for leak-free shutdown.
+
+package channerics
+
+import (
+	"sync"
+	"time"
+)
+
+// joiner tracks the goroutines spawned by a combinator.
+type joiner struct {
+	wg sync.WaitGroup
+}
+
+func (j *joiner) spawn(f func()) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		f()
+	}()
+}
+
+// exited returns a channel which closes once every spawned goroutine has exited.
+// It must be called after the combinator has spawned its initial goroutines, which
+// spawn any further goroutines before exiting.
+func (j *joiner) exited() <-chan struct{} {
+	exited := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(exited)
+	}()
+	return exited
+}
+
+// OrDoneJoin is OrDone, also returning a channel closed once its goroutines have exited.
+func OrDoneJoin[T any](done <-chan struct{}, vals <-chan T) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := orDone(j.spawn, done, vals)
+	return out, j.exited()
+}
+
+// MergeJoin is Merge, also returning a channel closed once its goroutines have exited.
+func MergeJoin[T any](done <-chan struct{}, inputs ...<-chan T) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := merge(j.spawn, done, inputs...)
+	return out, j.exited()
+}
+
+// BroadcastJoin is Broadcast, also returning a channel closed once its goroutines have exited.
+func BroadcastJoin[T any](done <-chan struct{}, input <-chan T, n int) ([]<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	outputs := broadcast(j.spawn, done, input, n)
+	return outputs, j.exited()
+}
+
+// TeeJoin is Tee, also returning a channel closed once its goroutines have exited.
+func TeeJoin[T any](done <-chan struct{}, in <-chan T) (<-chan T, <-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out1, out2 := tee(j.spawn, done, in)
+	return out1, out2, j.exited()
+}
+
+// GeneratorJoin is Generator, also returning a channel closed once its goroutines have exited.
+func GeneratorJoin[T any](done <-chan struct{}, generate func() (T, bool)) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := generator(j.spawn, done, generate)
+	return out, j.exited()
+}
+
+// RepeaterJoin is Repeater, also returning a channel closed once its goroutines have exited.
+func RepeaterJoin[T any](done <-chan struct{}, seq []T) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := repeater(j.spawn, done, seq)
+	return out, j.exited()
+}
+
+// ConvertJoin is Convert, also returning a channel closed once its goroutines have exited.
+func ConvertJoin[T1 any, T2 any](
+	done <-chan struct{},
+	vals <-chan T1,
+	convertFn func(T1) T2,
+) (<-chan T2, <-chan struct{}) {
+	j := &joiner{}
+	out := convert(j.spawn, done, vals, convertFn)
+	return out, j.exited()
+}
+
+// AsTypeJoin is AsType, also returning a channel closed once its goroutines have exited.
+func AsTypeJoin[T any](done <-chan struct{}, vals chan interface{}) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := asType[T](j.spawn, done, vals)
+	return out, j.exited()
+}
+
+// BridgeJoin is Bridge, also returning a channel closed once its goroutines have exited.
+func BridgeJoin[T any](done <-chan struct{}, chanStream <-chan <-chan T) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := bridge(j.spawn, done, chanStream)
+	return out, j.exited()
+}
+
+// ConcatJoin is Concat, also returning a channel closed once its goroutines have exited.
+func ConcatJoin[T any](done <-chan struct{}, chans ...<-chan T) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := concat(j.spawn, done, chans...)
+	return out, j.exited()
+}
+
+// FanOutJoin is FanOut, also returning a channel closed once its goroutines have exited.
+func FanOutJoin[T any](
+	done <-chan struct{},
+	in <-chan T,
+	n int,
+	strategy FanOutStrategy[T],
+) ([]<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	outputs := fanOut(j.spawn, done, in, n, strategy)
+	return outputs, j.exited()
+}
+
+// BatchJoin is Batch, also returning a channel closed once its goroutines have exited.
+func BatchJoin[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	maxSize int,
+	maxWait time.Duration,
+) (<-chan []T, <-chan struct{}) {
+	j := &joiner{}
+	out := batch(j.spawn, done, clock, in, maxSize, maxWait)
+	return out, j.exited()
+}
+
+// NewTickerJoin is NewTickerWithClock, also returning a channel closed once its goroutine
+// has exited.
+func NewTickerJoin(done <-chan struct{}, clock Clock, duration time.Duration) (chan time.Time, <-chan struct{}) {
+	j := &joiner{}
+	ticks := newTicker(j.spawn, done, clock, duration)
+	return ticks, j.exited()
+}
+
+// ThrottleJoin is Throttle, also returning a channel closed once its goroutines, including
+// those of its token bucket, have exited. The channel is nil if an error is returned.
+func ThrottleJoin[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	rate float64,
+	burst int,
+) (<-chan T, <-chan struct{}, error) {
+	j := &joiner{}
+	out, err := throttle(j.spawn, done, clock, in, rate, burst)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, j.exited(), nil
+}
+
+// DebounceJoin is Debounce, also returning a channel closed once its goroutines have exited.
+func DebounceJoin[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	quiet time.Duration,
+) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := debounce(j.spawn, done, clock, in, quiet)
+	return out, j.exited()
+}
+
+// SampleJoin is Sample, also returning a channel closed once its goroutines have exited.
+func SampleJoin[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	interval time.Duration,
+) (<-chan T, <-chan struct{}) {
+	j := &joiner{}
+	out := sample(j.spawn, done, clock, in, interval)
+	return out, j.exited()
+}
+
+// WithHeartbeatJoin is WithHeartbeat, also returning a channel closed once its goroutines,
+// including the one running work, have exited.
+func WithHeartbeatJoin(
+	done <-chan struct{},
+	clock Clock,
+	interval time.Duration,
+	work func(done <-chan struct{}, pulse func()),
+) (<-chan struct{}, <-chan struct{}) {
+	j := &joiner{}
+	heartbeat := withHeartbeat(j.spawn, done, clock, interval, work)
+	return heartbeat, j.exited()
+}
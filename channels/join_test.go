@@ -0,0 +1,155 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJoin(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	isClosed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		case <-time.After(maxWaitForEffect):
+			return false
+		}
+	}
+
+	isOpen := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return false
+		case <-time.After(time.Duration(10) * time.Millisecond):
+			return true
+		}
+	}
+
+	Convey("Join tests", t, func() {
+		Convey("When done is closed every combinator's goroutines exit", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			vals := make(chan interface{})
+
+			_, orDoneExited := OrDoneJoin(done, in)
+			_, mergeExited := MergeJoin(done, in, in)
+			_, broadcastExited := BroadcastJoin(done, in, 3)
+			_, _, teeExited := TeeJoin(done, in)
+			_, generatorExited := GeneratorJoin(done, func() (int, bool) { return 1, true })
+			_, repeaterExited := RepeaterJoin(done, []int{1, 2})
+			_, convertExited := ConvertJoin(done, in, func(i int) string { return "" })
+			_, asTypeExited := AsTypeJoin[int](done, vals)
+
+			exited := []<-chan struct{}{
+				orDoneExited,
+				mergeExited,
+				broadcastExited,
+				teeExited,
+				generatorExited,
+				repeaterExited,
+				convertExited,
+				asTypeExited,
+			}
+			for _, ch := range exited {
+				So(isOpen(ch), ShouldBeTrue)
+			}
+
+			close(done)
+			for _, ch := range exited {
+				So(isClosed(ch), ShouldBeTrue)
+			}
+		})
+
+		Convey("When done is closed every timed or reshaping combinator's goroutines exit", func() {
+			done := make(chan struct{})
+			clock := NewFakeClock(time.Unix(0, 0))
+			in := make(chan int)
+			chanStream := make(chan (<-chan int))
+
+			_, bridgeExited := BridgeJoin(done, chanStream)
+			_, concatExited := ConcatJoin(done, in, in)
+			_, fanOutExited := FanOutJoin(done, in, 2, RoundRobin[int]())
+			_, firstAvailableExited := FanOutJoin(done, in, 2, FirstAvailable[int]())
+			_, batchExited := BatchJoin(done, clock, in, 2, time.Second)
+			_, tickerExited := NewTickerJoin(done, clock, time.Second)
+			_, throttleExited, err := ThrottleJoin(done, clock, in, 1, 1)
+			So(err, ShouldBeNil)
+			_, debounceExited := DebounceJoin(done, clock, in, time.Second)
+			_, sampleExited := SampleJoin(done, clock, in, time.Second)
+			_, heartbeatExited := WithHeartbeatJoin(done, clock, time.Second, func(done <-chan struct{}, pulse func()) {
+				<-done
+			})
+
+			exited := []<-chan struct{}{
+				bridgeExited,
+				concatExited,
+				fanOutExited,
+				firstAvailableExited,
+				batchExited,
+				tickerExited,
+				throttleExited,
+				debounceExited,
+				sampleExited,
+				heartbeatExited,
+			}
+			for _, ch := range exited {
+				So(isOpen(ch), ShouldBeTrue)
+			}
+
+			close(done)
+			for _, ch := range exited {
+				So(isClosed(ch), ShouldBeTrue)
+			}
+		})
+
+		Convey("When Throttle's input is closed its token bucket's goroutines exit", func() {
+			in := make(chan int)
+			close(in)
+			out, exited, err := ThrottleJoin(nil, NewFakeClock(time.Unix(0, 0)), in, 1, 1)
+			So(err, ShouldBeNil)
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+			So(isClosed(exited), ShouldBeTrue)
+
+			out, exited, err = ThrottleJoin(nil, NewFakeClock(time.Unix(0, 0)), in, 1, 0)
+			So(err, ShouldEqual, ErrInvalidSize)
+			So(out, ShouldBeNil)
+			So(exited, ShouldBeNil)
+		})
+
+		Convey("When inputs are closed and drained the goroutines exit", func() {
+			in := make(chan int, 2)
+			in <- 1
+			in <- 2
+			close(in)
+
+			outs, exited := BroadcastJoin(nil, in, 2)
+			for _, out := range outs {
+				out := out
+				go func() {
+					for range out {
+					}
+				}()
+			}
+			So(isClosed(exited), ShouldBeTrue)
+		})
+
+		Convey("When an output is not drained the goroutines have not exited", func() {
+			in := make(chan int, 1)
+			in <- 1
+			out, exited := OrDoneJoin(nil, in)
+			So(isOpen(exited), ShouldBeTrue)
+
+			So(<-out, ShouldEqual, 1)
+			close(in)
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+			So(isClosed(exited), ShouldBeTrue)
+		})
+	})
+}
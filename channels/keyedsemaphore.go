@@ -0,0 +1,151 @@
+// Copyright 2022 Jesse Waite
+
+// keyedsemaphore.go is for per-key counting semaphores, i.e. lock striping.
+
+package channerics
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrTooManyKeys = errors.New("too many keys")
+
+// KeyedSemaphore grants up to n concurrent holders per key, such as a per-customer
+// concurrency cap. Each key in use is backed by its own Semaphore, which is discarded
+// as soon as the key has no holders or waiters, so idle keys consume no memory; the
+// number of keys in use at once is bounded by maxKeys.
+type KeyedSemaphore[K comparable] struct {
+	n       int
+	maxKeys int
+
+	mu     sync.Mutex
+	keys   map[K]*keyedEntry
+	closed chan struct{}
+	closer sync.Once
+}
+
+// keyedEntry is a key's semaphore and its number of holders and waiters.
+type keyedEntry struct {
+	sem  *Semaphore
+	refs int
+}
+
+// KeyedPermit is a permit for a single key, returned by KeyedSemaphore.Take.
+type KeyedPermit[K comparable] struct {
+	ks     *KeyedSemaphore[K]
+	key    K
+	permit *Permit
+}
+
+// NewKeyedSemaphore returns a KeyedSemaphore granting n holders per key, for up to maxKeys keys.
+// NewKeyedSemaphore returns ErrInvalidSize if n or maxKeys are not positive.
+func NewKeyedSemaphore[K comparable](n, maxKeys int) (*KeyedSemaphore[K], error) {
+	if n <= 0 || maxKeys <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	return &KeyedSemaphore[K]{
+		n:       n,
+		maxKeys: maxKeys,
+		keys:    make(map[K]*keyedEntry),
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+// Take blocks until a permit for key is available, returning ctx.Err() if ctx is cancelled
+// first, or ErrClosed if the semaphore is closed. Take immediately returns ErrTooManyKeys if
+// key is not in use and maxKeys keys already are.
+func (ks *KeyedSemaphore[K]) Take(ctx context.Context, key K) (*KeyedPermit[K], error) {
+	entry, err := ks.acquire(key)
+	if err != nil {
+		return nil, err
+	}
+
+	permit, err := entry.sem.Take(ctx)
+	if err != nil {
+		ks.unref(key)
+		return nil, err
+	}
+
+	return &KeyedPermit[K]{
+		ks:     ks,
+		key:    key,
+		permit: permit,
+	}, nil
+}
+
+// Len returns the number of keys in use, i.e. with holders or waiters.
+// Like Semaphore.UnsafeCount, the result is immediately expired.
+func (ks *KeyedSemaphore[K]) Len() int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return len(ks.keys)
+}
+
+// acquire returns key's entry, creating it if necessary, and counts the caller as a reference.
+func (ks *KeyedSemaphore[K]) acquire(key K) (*keyedEntry, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	select {
+	case <-ks.closed:
+		return nil, ErrClosed
+	default:
+	}
+
+	entry, ok := ks.keys[key]
+	if !ok {
+		if len(ks.keys) >= ks.maxKeys {
+			return nil, ErrTooManyKeys
+		}
+		entry = &keyedEntry{sem: NewSemaphore(ks.n)}
+		ks.keys[key] = entry
+	}
+	entry.refs++
+
+	return entry, nil
+}
+
+// unref removes a reference to key's entry, discarding the entry once it is idle.
+func (ks *KeyedSemaphore[K]) unref(key K) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	entry := ks.keys[key]
+	entry.refs--
+	if entry.refs == 0 {
+		delete(ks.keys, key)
+		entry.sem.Close()
+	}
+}
+
+// Close closes the semaphore, after which Take returns ErrClosed and blocked Take calls
+// are unblocked. Calls after the first return ErrClosed.
+func (ks *KeyedSemaphore[K]) Close() (err error) {
+	err = ErrClosed
+	ks.closer.Do(func() {
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+
+		close(ks.closed)
+		for _, entry := range ks.keys {
+			entry.sem.Close()
+		}
+		err = nil
+	})
+	return
+}
+
+// Release returns the permit to its key's semaphore. Like Permit.Release, it is idempotent
+// and returns ErrDoubleRelease on subsequent calls, or ErrClosed if the semaphore is closed.
+func (p *KeyedPermit[K]) Release() error {
+	err := p.permit.Release()
+	if err == ErrDoubleRelease {
+		return err
+	}
+
+	p.ks.unref(p.key)
+	return err
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeyedSemaphore(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	Convey("KeyedSemaphore tests", t, func() {
+		Convey("When invalid sizes are passed", func() {
+			ks, err := NewKeyedSemaphore[string](0, 1)
+			So(ks, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+
+			ks, err = NewKeyedSemaphore[string](1, 0)
+			So(ks, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+		})
+
+		Convey("When a key's holders are exhausted other keys are unaffected", func() {
+			ks, err := NewKeyedSemaphore[string](2, 10)
+			So(err, ShouldBeNil)
+			defer ks.Close()
+			ctx := context.Background()
+
+			_, err = ks.Take(ctx, "a")
+			So(err, ShouldBeNil)
+			first, err := ks.Take(ctx, "a")
+			So(err, ShouldBeNil)
+
+			// "a" is exhausted...
+			timeoutCtx, cancelFn := context.WithTimeout(ctx, time.Duration(10)*time.Millisecond)
+			defer cancelFn()
+			_, err = ks.Take(timeoutCtx, "a")
+			So(err, ShouldBeError, context.DeadlineExceeded)
+
+			// ...but "b" is not.
+			_, err = ks.Take(ctx, "b")
+			So(err, ShouldBeNil)
+
+			// Releasing a holder of "a" unblocks a waiter.
+			taken := make(chan error)
+			go func() {
+				_, err := ks.Take(ctx, "a")
+				taken <- err
+			}()
+			So(first.Release(), ShouldBeNil)
+			select {
+			case err = <-taken:
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When keys become idle they are discarded", func() {
+			ks, err := NewKeyedSemaphore[int](1, 10)
+			So(err, ShouldBeNil)
+			defer ks.Close()
+
+			permit1, err := ks.Take(context.Background(), 1)
+			So(err, ShouldBeNil)
+			permit2, err := ks.Take(context.Background(), 2)
+			So(err, ShouldBeNil)
+			So(ks.Len(), ShouldEqual, 2)
+
+			So(permit1.Release(), ShouldBeNil)
+			So(ks.Len(), ShouldEqual, 1)
+			So(permit1.Release(), ShouldBeError, ErrDoubleRelease)
+			So(ks.Len(), ShouldEqual, 1)
+			So(permit2.Release(), ShouldBeNil)
+			So(ks.Len(), ShouldEqual, 0)
+		})
+
+		Convey("When a waiter is cancelled its key is discarded", func() {
+			ks, err := NewKeyedSemaphore[int](1, 10)
+			So(err, ShouldBeNil)
+			defer ks.Close()
+
+			ctx, cancelFn := context.WithCancel(context.Background())
+			cancelFn()
+			_, err = ks.Take(ctx, 1)
+			So(err, ShouldBeError, context.Canceled)
+			So(ks.Len(), ShouldEqual, 0)
+		})
+
+		Convey("When the key count is exhausted", func() {
+			ks, err := NewKeyedSemaphore[int](1, 1)
+			So(err, ShouldBeNil)
+			defer ks.Close()
+
+			permit, err := ks.Take(context.Background(), 1)
+			So(err, ShouldBeNil)
+			_, err = ks.Take(context.Background(), 2)
+			So(err, ShouldBeError, ErrTooManyKeys)
+
+			So(permit.Release(), ShouldBeNil)
+			_, err = ks.Take(context.Background(), 2)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("When the semaphore is closed blocked callers are unblocked", func() {
+			ks, err := NewKeyedSemaphore[int](1, 10)
+			So(err, ShouldBeNil)
+			permit, err := ks.Take(context.Background(), 1)
+			So(err, ShouldBeNil)
+
+			taken := make(chan error)
+			go func() {
+				_, err := ks.Take(context.Background(), 1)
+				taken <- err
+			}()
+
+			// Await the waiter's reference to the key.
+			for i := 0; i < 250; i++ {
+				ks.mu.Lock()
+				refs := ks.keys[1].refs
+				ks.mu.Unlock()
+				if refs == 2 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			So(ks.Close(), ShouldBeNil)
+			So(ks.Close(), ShouldBeError, ErrClosed)
+			select {
+			case err = <-taken:
+				So(err, ShouldBeError, ErrClosed)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			_, err = ks.Take(context.Background(), 2)
+			So(err, ShouldBeError, ErrClosed)
+			So(permit.Release(), ShouldBeError, ErrClosed)
+		})
+	})
+}
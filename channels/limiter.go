@@ -0,0 +1,239 @@
+// Copyright 2022 Jesse Waite
+
+// limiter.go is for concurrency limiters whose limit changes at runtime, either when set
+// explicitly or adaptively per the outcomes of the calls they limit.
+
+package channerics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrInvalidAlgorithm = errors.New("limit algorithm parameters out of range")
+
+// LimitAlgorithm computes a Limiter's next limit from the outcome of a completed call.
+// Update is called while the limiter's lock is held, so implementations may keep
+// unsynchronized state, but must not call back into the limiter.
+type LimitAlgorithm interface {
+	Update(limit, inFlight int, success bool, latency time.Duration) int
+}
+
+// Limiter is a concurrency limiter whose limit can change at runtime. It is built on a
+// Semaphore sized to the maximum limit, of which the limiter itself holds the permits
+// above the current limit: shrinking the limit takes permits, and growing it releases them.
+// When the limit shrinks below the number of calls in flight, the limiter keeps the permits
+// of the calls that complete until the new limit is reached.
+type Limiter struct {
+	sem       *Semaphore
+	maxLimit  int
+	algorithm LimitAlgorithm
+
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	// reserved are the permits held by the limiter to enforce the limit, and debt is the
+	// number of permits it has yet to reclaim from in-flight calls.
+	reserved []*Permit
+	debt     int
+}
+
+// LimiterPermit is a slot taken from a Limiter, which must be returned by calling Done.
+type LimiterPermit struct {
+	limiter *Limiter
+	permit  *Permit
+	done    int32
+}
+
+// NewLimiter returns a Limiter whose limit may be changed using SetLimit, within [1, maxLimit].
+// NewLimiter returns ErrInvalidSize if limit is not within these bounds.
+func NewLimiter(limit, maxLimit int) (*Limiter, error) {
+	return NewAdaptiveLimiter(limit, maxLimit, nil)
+}
+
+// NewAdaptiveLimiter returns a Limiter whose limit is additionally updated per algorithm,
+// whenever a call reports its outcome via LimiterPermit.Done.
+// A nil algorithm yields a Limiter that is only changed by SetLimit. NewAdaptiveLimiter
+// returns ErrInvalidAlgorithm if an AIMD or Gradient algorithm's parameters are out of range,
+// e.g. those of their zero values.
+func NewAdaptiveLimiter(limit, maxLimit int, algorithm LimitAlgorithm) (*Limiter, error) {
+	if limit <= 0 || maxLimit < limit {
+		return nil, ErrInvalidSize
+	}
+	if v, ok := algorithm.(interface{ valid() bool }); ok && !v.valid() {
+		return nil, ErrInvalidAlgorithm
+	}
+
+	l := &Limiter{
+		sem:       NewSemaphore(maxLimit),
+		maxLimit:  maxLimit,
+		algorithm: algorithm,
+		limit:     maxLimit,
+	}
+	l.setLimit(limit)
+
+	return l, nil
+}
+
+// Take blocks until a slot is available under the current limit, returning ctx.Err() if
+// ctx is cancelled first, or ErrClosed if the limiter is closed.
+func (l *Limiter) Take(ctx context.Context) (*LimiterPermit, error) {
+	permit, err := l.sem.Take(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.inFlight++
+	l.mu.Unlock()
+
+	return &LimiterPermit{
+		limiter: l,
+		permit:  permit,
+	}, nil
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// SetLimit changes the limit to n, clamped to [1, maxLimit]. Growing the limit immediately
+// unblocks waiting callers, while shrinking it does not affect calls already in flight.
+func (l *Limiter) SetLimit(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setLimit(n)
+}
+
+// setLimit adjusts the reserved permits to enforce limit n; callers must hold the lock.
+func (l *Limiter) setLimit(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > l.maxLimit {
+		n = l.maxLimit
+	}
+
+	for ; l.limit < n; l.limit++ {
+		if l.debt > 0 {
+			l.debt--
+			continue
+		}
+		last := len(l.reserved) - 1
+		l.reserved[last].Release()
+		l.reserved = l.reserved[:last]
+	}
+
+	for ; l.limit > n; l.limit-- {
+		if permit, err := l.sem.MaybeTake(); err == nil {
+			l.reserved = append(l.reserved, permit)
+		} else {
+			l.debt++
+		}
+	}
+}
+
+// Close closes the limiter, after which Take returns ErrClosed.
+func (l *Limiter) Close() error {
+	return l.sem.Close()
+}
+
+// Done returns the permit's slot to the limiter, reporting whether the call succeeded and
+// its latency, which an adaptive limiter uses to update its limit. Done is idempotent:
+// subsequent calls report the misuse by returning ErrDoubleRelease.
+func (p *LimiterPermit) Done(success bool, latency time.Duration) error {
+	if !atomic.CompareAndSwapInt32(&p.done, 0, 1) {
+		return ErrDoubleRelease
+	}
+
+	l := p.limiter
+	l.mu.Lock()
+	if l.algorithm != nil {
+		l.setLimit(l.algorithm.Update(l.limit, l.inFlight, success, latency))
+	}
+	l.inFlight--
+	if l.debt > 0 {
+		// Reclaim the permit to enforce a limit that shrank while the call was in flight.
+		l.debt--
+		l.reserved = append(l.reserved, p.permit)
+		l.mu.Unlock()
+		return nil
+	}
+	l.mu.Unlock()
+
+	return p.permit.Release()
+}
+
+// AIMD is an additive-increase/multiplicative-decrease LimitAlgorithm, as in TCP congestion
+// control: the limit grows by one per successful call while at least half of it is in use,
+// and is multiplied by BackoffRatio when a call fails or its latency exceeds Timeout.
+type AIMD struct {
+	// BackoffRatio in (0, 1) is the factor by which the limit shrinks upon failure.
+	BackoffRatio float64
+	// Timeout, if positive, is the latency beyond which a call is deemed to have failed.
+	Timeout time.Duration
+}
+
+func (a AIMD) valid() bool {
+	return a.BackoffRatio > 0 && a.BackoffRatio < 1
+}
+
+func (a AIMD) Update(limit, inFlight int, success bool, latency time.Duration) int {
+	if !success || (a.Timeout > 0 && latency > a.Timeout) {
+		return int(float64(limit) * a.BackoffRatio)
+	}
+	if inFlight*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// Gradient is a LimitAlgorithm that compares each call's latency to the minimum latency
+// observed, taken to be the latency without queueing. While latencies stay near the minimum,
+// the limit grows by roughly its square root per call, allowing some queueing; as latencies
+// rise, the limit shrinks in proportion, down to half per call. Failed calls halve the limit.
+// The minimum latency is never reset, so Gradient suits dependencies whose unloaded latency
+// is stable. A Gradient must not be shared between limiters.
+type Gradient struct {
+	// Smoothing in (0, 1] weights each new estimate against the current limit.
+	Smoothing float64
+
+	minLatency time.Duration
+	estimate   float64
+}
+
+func (g *Gradient) valid() bool {
+	return g.Smoothing > 0 && g.Smoothing <= 1
+}
+
+func (g *Gradient) Update(limit, inFlight int, success bool, latency time.Duration) int {
+	// The estimate is kept as a float so small limits can grow, and re-synced if the
+	// limit was changed elsewhere, e.g. by SetLimit.
+	if int(g.estimate) != limit {
+		g.estimate = float64(limit)
+	}
+	if !success {
+		g.estimate /= 2
+		return int(g.estimate)
+	}
+
+	if g.minLatency == 0 || latency < g.minLatency {
+		g.minLatency = latency
+	}
+
+	gradient := 1.0
+	if latency > 0 {
+		gradient = math.Max(0.5, math.Min(1, float64(g.minLatency)/float64(latency)))
+	}
+	target := g.estimate*gradient + math.Sqrt(g.estimate)
+	g.estimate = g.estimate*(1-g.Smoothing) + target*g.Smoothing
+
+	return int(g.estimate)
+}
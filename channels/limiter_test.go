@@ -0,0 +1,212 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimiter(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	// takeAsync calls Take in a new goroutine and returns a channel yielding its permit.
+	takeAsync := func(l *Limiter) <-chan *LimiterPermit {
+		result := make(chan *LimiterPermit, 1)
+		go func() {
+			permit, _ := l.Take(context.Background())
+			result <- permit
+		}()
+		return result
+	}
+
+	// takeN takes n permits, each of which must be immediately available.
+	takeN := func(l *Limiter, n int) (permits []*LimiterPermit) {
+		for i := 0; i < n; i++ {
+			ctx, cancelFn := context.WithTimeout(context.Background(), maxWaitForEffect)
+			permit, err := l.Take(ctx)
+			cancelFn()
+			So(err, ShouldBeNil)
+			permits = append(permits, permit)
+		}
+		return
+	}
+
+	// isBlocked returns true if Take blocks.
+	isBlocked := func(l *Limiter) bool {
+		ctx, cancelFn := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+		defer cancelFn()
+		permit, err := l.Take(ctx)
+		if err == nil {
+			permit.Done(true, 0)
+			return false
+		}
+		return true
+	}
+
+	Convey("Limiter tests", t, func() {
+		Convey("When invalid limits are passed", func() {
+			l, err := NewLimiter(0, 1)
+			So(l, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+
+			l, err = NewLimiter(2, 1)
+			So(l, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+		})
+
+		Convey("When the limit is reached Take blocks until a permit is done", func() {
+			l, err := NewLimiter(2, 10)
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			permits := takeN(l, 2)
+			So(isBlocked(l), ShouldBeTrue)
+
+			So(permits[0].Done(true, 0), ShouldBeNil)
+			So(permits[0].Done(true, 0), ShouldBeError, ErrDoubleRelease)
+			So(isBlocked(l), ShouldBeFalse)
+		})
+
+		Convey("When the limit grows waiting callers are unblocked", func() {
+			l, err := NewLimiter(1, 10)
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			takeN(l, 1)
+			waiter := takeAsync(l)
+			l.SetLimit(2)
+			So(l.Limit(), ShouldEqual, 2)
+
+			select {
+			case permit := <-waiter:
+				So(permit, ShouldNotBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When the limit shrinks below the calls in flight their permits are reclaimed", func() {
+			l, err := NewLimiter(3, 10)
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			permits := takeN(l, 3)
+			l.SetLimit(1)
+			So(l.Limit(), ShouldEqual, 1)
+
+			// Two completions pay down the limiter's debt, and only the third frees a slot.
+			So(permits[0].Done(true, 0), ShouldBeNil)
+			So(permits[1].Done(true, 0), ShouldBeNil)
+			So(isBlocked(l), ShouldBeTrue)
+			So(permits[2].Done(true, 0), ShouldBeNil)
+			So(isBlocked(l), ShouldBeFalse)
+
+			// Once the debt is paid, growing the limit releases reserved permits.
+			l.SetLimit(3)
+			takeN(l, 3)
+			So(isBlocked(l), ShouldBeTrue)
+		})
+
+		Convey("When the limit grows while debt is outstanding the debt is forgiven first", func() {
+			l, err := NewLimiter(3, 10)
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			permits := takeN(l, 3)
+			l.SetLimit(1)
+			l.SetLimit(2)
+			So(l.Limit(), ShouldEqual, 2)
+
+			// One completion pays down the remaining debt, and the next frees a slot.
+			So(permits[0].Done(true, 0), ShouldBeNil)
+			So(isBlocked(l), ShouldBeTrue)
+			So(permits[1].Done(true, 0), ShouldBeNil)
+			takeN(l, 1)
+			So(isBlocked(l), ShouldBeTrue)
+		})
+
+		Convey("When SetLimit is called beyond the bounds the limit is clamped", func() {
+			l, err := NewLimiter(3, 5)
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			l.SetLimit(0)
+			So(l.Limit(), ShouldEqual, 1)
+			l.SetLimit(100)
+			So(l.Limit(), ShouldEqual, 5)
+		})
+
+		Convey("When the limiter is closed", func() {
+			l, err := NewLimiter(1, 1)
+			So(err, ShouldBeNil)
+			permits := takeN(l, 1)
+
+			So(l.Close(), ShouldBeNil)
+			_, err = l.Take(context.Background())
+			So(err, ShouldBeError, ErrClosed)
+			So(permits[0].Done(true, 0), ShouldBeError, ErrClosed)
+		})
+
+		Convey("When an AIMD limiter observes outcomes", func() {
+			l, err := NewAdaptiveLimiter(4, 100, AIMD{BackoffRatio: 0.5, Timeout: time.Second})
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			// Successes while less than half the limit is in use leave it unchanged.
+			permits := takeN(l, 1)
+			So(permits[0].Done(true, time.Millisecond), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 4)
+
+			// Successes while at least half the limit is in use grow the limit additively.
+			permits = takeN(l, 2)
+			So(permits[0].Done(true, time.Millisecond), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 5)
+
+			// Failures and timeouts shrink it multiplicatively.
+			So(permits[1].Done(false, time.Millisecond), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 2)
+			permits = takeN(l, 1)
+			So(permits[0].Done(true, time.Minute), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 1)
+		})
+
+		Convey("When an algorithm's parameters are out of range", func() {
+			algorithms := []LimitAlgorithm{
+				AIMD{},
+				AIMD{BackoffRatio: 1},
+				&Gradient{},
+				&Gradient{Smoothing: 1.5},
+			}
+			for _, algorithm := range algorithms {
+				l, err := NewAdaptiveLimiter(4, 100, algorithm)
+				So(l, ShouldBeNil)
+				So(err, ShouldBeError, ErrInvalidAlgorithm)
+			}
+		})
+
+		Convey("When a Gradient limiter observes rising latency the limit shrinks", func() {
+			l, err := NewAdaptiveLimiter(16, 100, &Gradient{Smoothing: 1})
+			So(err, ShouldBeNil)
+			defer l.Close()
+
+			// Latency at the minimum grows the limit by its square root.
+			permits := takeN(l, 1)
+			So(permits[0].Done(true, time.Millisecond), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 20)
+
+			// Latency at twice the minimum halves it, plus the square root.
+			permits = takeN(l, 1)
+			So(permits[0].Done(true, time.Duration(2)*time.Millisecond), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 14)
+
+			// Failure halves it.
+			permits = takeN(l, 1)
+			So(permits[0].Done(false, time.Millisecond), ShouldBeNil)
+			So(l.Limit(), ShouldEqual, 7)
+		})
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 Jesse Waite
+
+// liveness.go contains patterns for heartbeats, steward/ward, and similar patterns.
+// These are useful in daemons, edge processes, and in layers of a system that depend on some
+// potentially misbehaving external dependency that could stop work; another instance might
+// be a numerical algorithm that gets stuck in a plateau/basin.
+
+package channerics
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrInvalidTimeout = errors.New("timeout must be positive")
+
+// WithHeartbeat runs work in a new goroutine and returns a heartbeat channel by which
+// the rest of the process can observe that work is alive. Work is passed done and a
+// pulse function, which it should call per unit of work; additionally, if interval > 0
+// the heartbeat is also pulsed every interval until work returns or done is closed.
+// Pulses never block the worker: the heartbeat channel has a buffer of one, and pulses
+// are dropped when nobody is listening, per the non-blocking send idiom:
+//
+//	select {
+//	case heartbeat <- struct{}{}:
+//	default:
+//	}
+//
+// The heartbeat closes after work returns. Work must honor done and must not call pulse
+// after returning, e.g. from a goroutine it started.
+func WithHeartbeat(
+	done <-chan struct{},
+	clock Clock,
+	interval time.Duration,
+	work func(done <-chan struct{}, pulse func()),
+) <-chan struct{} {
+	return withHeartbeat(goAsync, done, clock, interval, work)
+}
+
+func withHeartbeat(
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	interval time.Duration,
+	work func(done <-chan struct{}, pulse func()),
+) <-chan struct{} {
+	heartbeat := make(chan struct{}, 1)
+	pulse := func() {
+		select {
+		case heartbeat <- struct{}{}:
+		default:
+		}
+	}
+
+	workDone := make(chan struct{})
+	var wg sync.WaitGroup
+	if interval > 0 {
+		ticker := newTicker(spawn, eitherDone(spawn, done, workDone), clock, interval)
+		wg.Add(1)
+		spawn(func() {
+			defer wg.Done()
+			for range ticker {
+				pulse()
+			}
+		})
+	}
+
+	spawn(func() {
+		defer close(heartbeat)
+		// The interval pulses must also stop before the heartbeat can be closed.
+		defer wg.Wait()
+		defer close(workDone)
+		work(done, pulse)
+	})
+
+	return heartbeat
+}
+
+// StewardEventKind enumerates the events reported by a Steward.
+type StewardEventKind int
+
+const (
+	// WardRestarted indicates that the ward missed its heartbeat deadline, had its done
+	// channel closed, and was restarted after a backoff.
+	WardRestarted StewardEventKind = iota
+	// WardAbandoned indicates that the ward missed its heartbeat deadline after the restart
+	// budget was spent; the steward has given up and will not restart it again.
+	WardAbandoned
+)
+
+// StewardEvent reports a restart or give-up by a Steward.
+type StewardEvent struct {
+	Kind StewardEventKind
+	// Restarts is the number of restarts performed so far, including this one.
+	Restarts int
+	// Backoff is the delay that preceded a restart.
+	Backoff time.Duration
+}
+
+// Steward launches ward via WithHeartbeat and watches its heartbeat. When no pulse arrives
+// within timeout, the ward is deemed unhealthy: its done channel is closed and a new ward is
+// started, after a backoff that begins at backoff and doubles per restart up to maxBackoff
+// (if maxBackoff > 0). After maxRestarts restarts, an unhealthy ward is abandoned instead.
+// Restarts and abandonment are reported on the returned channel, which must be drained, and
+// which closes when done is closed, when the ward is abandoned, or when the ward returns of its
+// own accord, which the steward takes to mean its work is complete.
+// The steward does not wait for an unhealthy ward to exit, since it may be hung; wards must
+// honor done for their goroutines to be reclaimed. Steward returns ErrInvalidTimeout if
+// timeout is not positive, since every ward would then be deemed unhealthy immediately.
+func Steward(
+	done <-chan struct{},
+	clock Clock,
+	timeout time.Duration,
+	backoff time.Duration,
+	maxBackoff time.Duration,
+	maxRestarts int,
+	ward func(done <-chan struct{}, pulse func()),
+) (<-chan StewardEvent, error) {
+	if timeout <= 0 {
+		return nil, ErrInvalidTimeout
+	}
+
+	events := make(chan StewardEvent)
+
+	// report sends an event and returns false if done was closed.
+	report := func(event StewardEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	// monitor runs a single ward and returns true if it missed its deadline,
+	// false if it returned or done was closed.
+	monitor := func() (unhealthy bool) {
+		wardDone := make(chan struct{})
+		defer close(wardDone)
+		heartbeat := WithHeartbeat(Any(done, wardDone), clock, 0, ward)
+
+		timer := clock.NewTimer(timeout)
+		defer func() {
+			timer.Stop()
+		}()
+		for {
+			select {
+			case _, ok := <-heartbeat:
+				if !ok {
+					return false
+				}
+				// A new timer, rather than Reset, such that an expiry already pending on the
+				// previous timer's channel is simply abandoned.
+				timer.Stop()
+				timer = clock.NewTimer(timeout)
+			case <-timer.C():
+				return true
+			case <-done:
+				return false
+			}
+		}
+	}
+
+	go func() {
+		defer close(events)
+
+		delay := backoff
+		for restarts := 0; monitor(); restarts++ {
+			if restarts >= maxRestarts {
+				report(StewardEvent{Kind: WardAbandoned, Restarts: restarts})
+				return
+			}
+
+			wait := clock.NewTimer(delay)
+			select {
+			case <-wait.C():
+			case <-done:
+				wait.Stop()
+				return
+			}
+
+			if !report(StewardEvent{Kind: WardRestarted, Restarts: restarts + 1, Backoff: delay}) {
+				return
+			}
+
+			delay *= 2
+			if maxBackoff > 0 && delay > maxBackoff {
+				delay = maxBackoff
+			}
+		}
+	}()
+
+	return events, nil
+}
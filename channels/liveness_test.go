@@ -0,0 +1,271 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithHeartbeat(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("WithHeartbeat tests", t, func() {
+		Convey("When done is already closed the heartbeat closes", func() {
+			done := make(chan struct{})
+			close(done)
+			heartbeat := WithHeartbeat(done, NewFakeClock(epoch), time.Millisecond, func(done <-chan struct{}, pulse func()) {
+				<-done
+			})
+
+			closed := false
+			for !closed {
+				select {
+				case _, ok := <-heartbeat:
+					closed = !ok
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			So(closed, ShouldBeTrue)
+		})
+
+		Convey("When work pulses per unit of work", func() {
+			done := make(chan struct{})
+			defer close(done)
+			units := make(chan int)
+			heartbeat := WithHeartbeat(done, NewFakeClock(epoch), 0, func(done <-chan struct{}, pulse func()) {
+				for range OrDone(done, units) {
+					pulse()
+				}
+			})
+
+			for i := 0; i < 3; i++ {
+				units <- i
+				select {
+				case _, ok := <-heartbeat:
+					So(ok, ShouldBeTrue)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+
+			close(units)
+			select {
+			case _, ok := <-heartbeat:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When nobody listens the worker is not blocked", func() {
+			done := make(chan struct{})
+			defer close(done)
+			workDone := make(chan struct{})
+			heartbeat := WithHeartbeat(done, NewFakeClock(epoch), 0, func(done <-chan struct{}, pulse func()) {
+				defer close(workDone)
+				for i := 0; i < 100; i++ {
+					pulse()
+				}
+			})
+
+			select {
+			case <-workDone:
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			// One pulse remains buffered, then the heartbeat closes.
+			_, ok := <-heartbeat
+			So(ok, ShouldBeTrue)
+			_, ok = <-heartbeat
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When an interval is given the heartbeat pulses while work is idle", func() {
+			done := make(chan struct{})
+			clock := NewFakeClock(epoch)
+			heartbeat := WithHeartbeat(done, clock, time.Millisecond, func(done <-chan struct{}, pulse func()) {
+				<-done
+			})
+
+			for i := 0; i < 3; i++ {
+				_, ok := advanceUntil(t, clock, time.Millisecond, heartbeat)
+				So(ok, ShouldBeTrue)
+			}
+
+			close(done)
+			closed := false
+			for !closed {
+				select {
+				case _, ok := <-heartbeat:
+					closed = !ok
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			So(closed, ShouldBeTrue)
+		})
+	})
+}
+
+func TestSteward(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	timeout := time.Duration(20) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("Steward tests", t, func() {
+		Convey("When done is already closed the events close", func() {
+			done := make(chan struct{})
+			close(done)
+			events, err := Steward(done, NewFakeClock(epoch), timeout, time.Millisecond, 0, 3, func(done <-chan struct{}, pulse func()) {
+				<-done
+			})
+			So(err, ShouldBeNil)
+
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When the timeout is not positive", func() {
+			for _, timeout := range []time.Duration{0, -time.Second} {
+				events, err := Steward(nil, NewFakeClock(epoch), timeout, 0, 0, 3, func(done <-chan struct{}, pulse func()) {})
+				So(events, ShouldBeNil)
+				So(err, ShouldBeError, ErrInvalidTimeout)
+			}
+		})
+
+		Convey("When done is closed during a backoff the events close", func() {
+			done := make(chan struct{})
+			clock := NewFakeClock(epoch)
+			events, err := Steward(done, clock, timeout, time.Hour, 0, 3, func(done <-chan struct{}, pulse func()) {
+				<-done
+			})
+			So(err, ShouldBeNil)
+
+			clock.BlockUntil(1)
+			clock.Advance(timeout)
+			// Await the backoff's timer.
+			clock.BlockUntil(1)
+			close(done)
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When done is closed while an event awaits its consumer the events close", func() {
+			done := make(chan struct{})
+			clock := NewFakeClock(epoch)
+			events, err := Steward(done, clock, timeout, time.Millisecond, 0, 3, func(done <-chan struct{}, pulse func()) {
+				<-done
+			})
+			So(err, ShouldBeNil)
+
+			clock.BlockUntil(1)
+			clock.Advance(timeout)
+			clock.BlockUntil(1)
+			clock.Advance(time.Millisecond)
+			// Let the steward block on reporting the restart, then close done instead of reading it.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			close(done)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When the ward returns the events close", func() {
+			done := make(chan struct{})
+			defer close(done)
+			events, err := Steward(done, NewFakeClock(epoch), timeout, time.Millisecond, 0, 3, func(done <-chan struct{}, pulse func()) {
+				pulse()
+			})
+			So(err, ShouldBeNil)
+
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When a healthy ward pulses it is not restarted", func() {
+			done := make(chan struct{})
+			// A generous timeout avoids spurious restarts when the scheduler is under load.
+			events, err := Steward(done, SystemClock{}, maxWaitForEffect, time.Millisecond, 0, 3, func(done <-chan struct{}, pulse func()) {
+				for range NewTicker(done, time.Millisecond) {
+					pulse()
+				}
+			})
+			So(err, ShouldBeNil)
+
+			select {
+			case <-events:
+				t.FailNow()
+			case <-time.After(maxWaitForEffect * 2):
+			}
+
+			close(done)
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When the ward hangs it is cancelled and restarted with backoff until abandoned", func() {
+			done := make(chan struct{})
+			defer close(done)
+			starts := make(chan struct{}, 10)
+			cancellations := make(chan struct{}, 10)
+			clock := NewFakeClock(epoch)
+			events, err := Steward(done, clock, timeout, time.Millisecond, time.Duration(3)*time.Millisecond, 3, func(done <-chan struct{}, pulse func()) {
+				starts <- struct{}{}
+				pulse()
+				// Hang until cancelled.
+				<-done
+				cancellations <- struct{}{}
+			})
+			So(err, ShouldBeNil)
+
+			expected := []StewardEvent{
+				{Kind: WardRestarted, Restarts: 1, Backoff: time.Millisecond},
+				{Kind: WardRestarted, Restarts: 2, Backoff: time.Duration(2) * time.Millisecond},
+				{Kind: WardRestarted, Restarts: 3, Backoff: time.Duration(3) * time.Millisecond},
+				{Kind: WardAbandoned, Restarts: 3},
+			}
+			for _, event := range expected {
+				e, ok := advanceUntil(t, clock, time.Millisecond, events)
+				So(ok, ShouldBeTrue)
+				So(e, ShouldResemble, event)
+			}
+
+			_, ok := advanceUntil(t, clock, time.Millisecond, events)
+			So(ok, ShouldBeFalse)
+			So(len(starts), ShouldEqual, 4)
+			// Each ward's done channel was closed.
+			for i := 0; i < 4; i++ {
+				select {
+				case <-cancellations:
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+		})
+	})
+}
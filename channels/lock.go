@@ -0,0 +1,225 @@
+// Copyright 2022 Jesse Waite
+
+// lock.go is for context-aware mutual exclusion locks, which unlike sync.Mutex and
+// sync.RWMutex can be abandoned while waiting. Timeouts are given via context.WithTimeout.
+
+package channerics
+
+import "context"
+
+// Mutex is a mutual exclusion lock implemented using a buffered channel of size one,
+// whose Lock may be cancelled. The zero value is not usable; use NewMutex.
+type Mutex struct {
+	ch chan struct{}
+}
+
+// NewMutex returns an unlocked Mutex.
+func NewMutex() *Mutex {
+	return &Mutex{
+		ch: make(chan struct{}, 1),
+	}
+}
+
+// Lock blocks until the lock is acquired, returning nil, or until ctx is cancelled,
+// returning ctx.Err(). As elsewhere in this package, cancellation has precedence.
+func (m *Mutex) Lock(ctx context.Context) error {
+	// Done-guard: cancellation has precedence over an available lock.
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryLock acquires the lock if doing so would not block, and returns whether it did.
+func (m *Mutex) TryLock() bool {
+	select {
+	case m.ch <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock releases the lock. Like sync.Mutex, it panics if the lock is not held.
+func (m *Mutex) Unlock() {
+	select {
+	case <-m.ch:
+	default:
+		panic("channerics: unlock of unlocked Mutex")
+	}
+}
+
+// RWLock is a reader/writer lock whose Lock and RLock may be cancelled. It prefers writers:
+// once a writer is waiting, new readers wait until no writers are waiting, so that a steady
+// stream of readers cannot starve writers. The zero value is not usable; use NewRWLock.
+//
+// The lock's state is itself guarded by a buffered channel of size one, and waiters block on
+// channels which are closed, and replaced, to wake them whenever the state changes.
+type RWLock struct {
+	state chan *rwState
+}
+
+type rwState struct {
+	readers        int
+	writer         bool
+	writersWaiting int
+	// readerWake and writerWake are closed to wake waiting readers or writers.
+	readerWake chan struct{}
+	writerWake chan struct{}
+}
+
+// NewRWLock returns an unlocked RWLock.
+func NewRWLock() *RWLock {
+	l := &RWLock{
+		state: make(chan *rwState, 1),
+	}
+	l.state <- &rwState{
+		readerWake: make(chan struct{}),
+		writerWake: make(chan struct{}),
+	}
+	return l
+}
+
+// wakeReaders wakes all waiting readers; callers must hold the state.
+func (s *rwState) wakeReaders() {
+	close(s.readerWake)
+	s.readerWake = make(chan struct{})
+}
+
+// wakeWriters wakes all waiting writers; callers must hold the state.
+func (s *rwState) wakeWriters() {
+	close(s.writerWake)
+	s.writerWake = make(chan struct{})
+}
+
+// Lock blocks until the lock is acquired for writing, returning nil, or until ctx is
+// cancelled, returning ctx.Err().
+func (l *RWLock) Lock(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s := <-l.state
+	if !s.writer && s.readers == 0 {
+		s.writer = true
+		l.state <- s
+		return nil
+	}
+	s.writersWaiting++
+
+	for {
+		wake := s.writerWake
+		l.state <- s
+
+		select {
+		case <-wake:
+			s = <-l.state
+			if !s.writer && s.readers == 0 {
+				s.writer = true
+				s.writersWaiting--
+				l.state <- s
+				return nil
+			}
+		case <-ctx.Done():
+			s = <-l.state
+			s.writersWaiting--
+			if s.writersWaiting == 0 && !s.writer {
+				// Readers held back in favor of this writer may now proceed.
+				s.wakeReaders()
+			}
+			l.state <- s
+			return ctx.Err()
+		}
+	}
+}
+
+// TryLock acquires the lock for writing if doing so would not block, and returns whether it did.
+func (l *RWLock) TryLock() bool {
+	s := <-l.state
+	defer func() { l.state <- s }()
+
+	if s.writer || s.readers > 0 {
+		return false
+	}
+	s.writer = true
+	return true
+}
+
+// Unlock releases the lock for writing. It panics if the lock is not held for writing.
+func (l *RWLock) Unlock() {
+	s := <-l.state
+	defer func() { l.state <- s }()
+
+	if !s.writer {
+		panic("channerics: unlock of unlocked RWLock")
+	}
+	s.writer = false
+	if s.writersWaiting > 0 {
+		s.wakeWriters()
+	} else {
+		s.wakeReaders()
+	}
+}
+
+// RLock blocks until the lock is acquired for reading, returning nil, or until ctx is
+// cancelled, returning ctx.Err().
+func (l *RWLock) RLock(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	for {
+		s := <-l.state
+		if !s.writer && s.writersWaiting == 0 {
+			s.readers++
+			l.state <- s
+			return nil
+		}
+		wake := s.readerWake
+		l.state <- s
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TryRLock acquires the lock for reading if doing so would not block, and returns whether it did.
+func (l *RWLock) TryRLock() bool {
+	s := <-l.state
+	defer func() { l.state <- s }()
+
+	if s.writer || s.writersWaiting > 0 {
+		return false
+	}
+	s.readers++
+	return true
+}
+
+// RUnlock releases a single read lock. It panics if the lock is not held for reading.
+func (l *RWLock) RUnlock() {
+	s := <-l.state
+	defer func() { l.state <- s }()
+
+	if s.readers == 0 {
+		panic("channerics: RUnlock of unlocked RWLock")
+	}
+	s.readers--
+	if s.readers == 0 && s.writersWaiting > 0 {
+		s.wakeWriters()
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMutex(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	Convey("Mutex tests", t, func() {
+		Convey("When the mutex is locked TryLock fails and Lock blocks", func() {
+			m := NewMutex()
+			So(m.Lock(context.Background()), ShouldBeNil)
+			So(m.TryLock(), ShouldBeFalse)
+
+			locked := make(chan error)
+			go func() {
+				locked <- m.Lock(context.Background())
+			}()
+			select {
+			case <-locked:
+				t.FailNow()
+			case <-time.After(time.Duration(10) * time.Millisecond):
+			}
+
+			m.Unlock()
+			select {
+			case err := <-locked:
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When Lock times out", func() {
+			m := NewMutex()
+			So(m.TryLock(), ShouldBeTrue)
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+			defer cancelFn()
+			So(m.Lock(ctx), ShouldBeError, context.DeadlineExceeded)
+		})
+
+		Convey("When cancelled before Lock, cancellation has precedence", func() {
+			m := NewMutex()
+			ctx, cancelFn := context.WithCancel(context.Background())
+			cancelFn()
+			So(m.Lock(ctx), ShouldBeError, context.Canceled)
+			So(m.TryLock(), ShouldBeTrue)
+		})
+
+		Convey("When an unlocked mutex is unlocked", func() {
+			m := NewMutex()
+			So(m.Unlock, ShouldPanic)
+		})
+	})
+}
+
+func TestRWLock(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	// lockAsync calls lockFn in a new goroutine and returns a channel yielding its result.
+	lockAsync := func(ctx context.Context, lockFn func(context.Context) error) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- lockFn(ctx)
+		}()
+		return result
+	}
+
+	// awaitWritersWaiting waits until n writers are waiting.
+	awaitWritersWaiting := func(l *RWLock, n int) {
+		for i := 0; i < 250; i++ {
+			s := <-l.state
+			waiting := s.writersWaiting
+			l.state <- s
+			if waiting >= n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.FailNow()
+	}
+
+	expectResult := func(result <-chan error, expected error) {
+		select {
+		case err := <-result:
+			So(errors.Is(err, expected), ShouldBeTrue)
+		case <-time.After(maxWaitForEffect):
+			t.FailNow()
+		}
+	}
+
+	expectBlocked := func(result <-chan error) {
+		select {
+		case <-result:
+			t.FailNow()
+		case <-time.After(time.Duration(10) * time.Millisecond):
+		}
+	}
+
+	Convey("RWLock tests", t, func() {
+		Convey("When readers hold the lock other readers may share it, but writers may not", func() {
+			l := NewRWLock()
+			ctx := context.Background()
+			So(l.RLock(ctx), ShouldBeNil)
+			So(l.TryRLock(), ShouldBeTrue)
+			So(l.TryLock(), ShouldBeFalse)
+
+			writer := lockAsync(ctx, l.Lock)
+			expectBlocked(writer)
+
+			l.RUnlock()
+			expectBlocked(writer)
+			l.RUnlock()
+			expectResult(writer, nil)
+
+			// Now readers are excluded by the writer.
+			So(l.TryRLock(), ShouldBeFalse)
+			reader := lockAsync(ctx, l.RLock)
+			expectBlocked(reader)
+			l.Unlock()
+			expectResult(reader, nil)
+		})
+
+		Convey("When a writer is waiting new readers wait behind it", func() {
+			l := NewRWLock()
+			ctx := context.Background()
+			So(l.RLock(ctx), ShouldBeNil)
+
+			writer := lockAsync(ctx, l.Lock)
+			awaitWritersWaiting(l, 1)
+
+			So(l.TryRLock(), ShouldBeFalse)
+			reader := lockAsync(ctx, l.RLock)
+			expectBlocked(reader)
+
+			l.RUnlock()
+			expectResult(writer, nil)
+			expectBlocked(reader)
+			l.Unlock()
+			expectResult(reader, nil)
+		})
+
+		Convey("When a waiting writer times out the readers behind it proceed", func() {
+			l := NewRWLock()
+			So(l.RLock(context.Background()), ShouldBeNil)
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), time.Duration(20)*time.Millisecond)
+			defer cancelFn()
+			writer := lockAsync(ctx, l.Lock)
+			awaitWritersWaiting(l, 1)
+			reader := lockAsync(context.Background(), l.RLock)
+
+			expectResult(writer, context.DeadlineExceeded)
+			expectResult(reader, nil)
+		})
+
+		Convey("When several writers wait they acquire the lock in turn", func() {
+			l := NewRWLock()
+			ctx := context.Background()
+			So(l.Lock(ctx), ShouldBeNil)
+
+			writer1 := lockAsync(ctx, l.Lock)
+			writer2 := lockAsync(ctx, l.Lock)
+			awaitWritersWaiting(l, 2)
+
+			l.Unlock()
+			var next <-chan error
+			select {
+			case err := <-writer1:
+				So(err, ShouldBeNil)
+				next = writer2
+			case err := <-writer2:
+				So(err, ShouldBeNil)
+				next = writer1
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			expectBlocked(next)
+			l.Unlock()
+			expectResult(next, nil)
+		})
+
+		Convey("When cancelled before locking", func() {
+			l := NewRWLock()
+			ctx, cancelFn := context.WithCancel(context.Background())
+			cancelFn()
+			So(l.Lock(ctx), ShouldBeError, context.Canceled)
+			So(l.RLock(ctx), ShouldBeError, context.Canceled)
+
+			// A reader waiting on a writer may also be cancelled.
+			So(l.TryLock(), ShouldBeTrue)
+			ctx, cancelFn = context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+			defer cancelFn()
+			So(l.RLock(ctx), ShouldBeError, context.DeadlineExceeded)
+		})
+
+		Convey("When unlocked locks are unlocked", func() {
+			l := NewRWLock()
+			So(l.Unlock, ShouldPanic)
+			So(l.RUnlock, ShouldPanic)
+			// The lock remains usable after misuse.
+			So(l.TryLock(), ShouldBeTrue)
+		})
+	})
+}
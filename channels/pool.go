@@ -0,0 +1,271 @@
+// Copyright 2022 Jesse Waite
+
+// pool.go is for bounded object pools, such as connection pools. Unlike a FreeList,
+// which only caches items and allocates on a miss, a Pool bounds the number of live
+// items and blocks borrowers when they are all in use.
+
+package channerics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PoolOptions are the optional hooks and settings of a Pool. The zero value is valid.
+type PoolOptions[T any] struct {
+	// Validate, if non-nil, is called on an idle item before it is borrowed; items for
+	// which it returns false are destroyed, and another item is borrowed or created.
+	Validate func(T) bool
+	// Reset, if non-nil, is called on an item when it is returned, before it becomes idle.
+	Reset func(T)
+	// Destroy, if non-nil, is called on every item the pool discards: items that fail
+	// validation, expire, are discarded by the borrower, or remain when the pool is closed.
+	Destroy func(T)
+	// IdleTimeout, if positive, is the duration after which an idle item expires and
+	// is destroyed.
+	IdleTimeout time.Duration
+}
+
+// Pool is a bounded pool of reusable items. At most maxLive items exist at once,
+// whether idle in the pool or borrowed from it. Idle items are borrowed in the order
+// in which they were returned. The pool counts outstanding borrows, and Put or Discard
+// panic if more items are returned than are borrowed, since this is always a bug.
+type Pool[T any] struct {
+	clock  Clock
+	create func(ctx context.Context) (T, error)
+	opts   PoolOptions[T]
+	// slots holds a token for each live item, and idle holds the items not borrowed.
+	slots  chan struct{}
+	idle   chan pooledItem[T]
+	closed chan struct{}
+	// reaped is closed when the idle item reaper exits.
+	reaped chan struct{}
+	closer sync.Once
+
+	mu       sync.Mutex
+	borrowed int
+}
+
+type pooledItem[T any] struct {
+	item T
+	// since is the time at which the item was returned to the pool.
+	since time.Time
+}
+
+// NewPool returns a Pool of at most maxLive items, which are created by calling create.
+// NewPool returns ErrInvalidSize if maxLive is not positive. If opts.IdleTimeout is positive
+// a goroutine destroys expired items every IdleTimeout, until the pool is closed.
+func NewPool[T any](
+	clock Clock,
+	maxLive int,
+	create func(ctx context.Context) (T, error),
+	opts PoolOptions[T],
+) (*Pool[T], error) {
+	if maxLive <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	p := &Pool[T]{
+		clock:  clock,
+		create: create,
+		opts:   opts,
+		slots:  make(chan struct{}, maxLive),
+		idle:   make(chan pooledItem[T], maxLive),
+		closed: make(chan struct{}),
+		reaped: make(chan struct{}),
+	}
+
+	if opts.IdleTimeout > 0 {
+		go p.reap()
+	} else {
+		close(p.reaped)
+	}
+
+	return p, nil
+}
+
+// Get borrows an idle item if one is available, creates an item if fewer than maxLive
+// exist, and otherwise blocks until an item is returned or discarded. Get returns ctx.Err()
+// if ctx is cancelled, or ErrClosed if the pool is closed, before an item is available.
+// Errors returned by create are returned as is. Borrowed items must be returned to the pool
+// by calling Put, or Discard.
+func (p *Pool[T]) Get(ctx context.Context) (t T, err error) {
+	for {
+		// Done-guard: closure and cancellation have precedence over an available item.
+		select {
+		case <-ctx.Done():
+			return t, ctx.Err()
+		case <-p.closed:
+			return t, ErrClosed
+		default:
+		}
+
+		// Prefer idle items to creating new ones.
+		select {
+		case pooled := <-p.idle:
+			if p.usable(pooled) {
+				p.borrow()
+				return pooled.item, nil
+			}
+			continue
+		default:
+		}
+
+		select {
+		case pooled := <-p.idle:
+			if p.usable(pooled) {
+				p.borrow()
+				return pooled.item, nil
+			}
+		case p.slots <- struct{}{}:
+			if t, err = p.create(ctx); err != nil {
+				<-p.slots
+				return
+			}
+			p.borrow()
+			return
+		case <-ctx.Done():
+			return t, ctx.Err()
+		case <-p.closed:
+			return t, ErrClosed
+		}
+	}
+}
+
+// usable returns whether an idle item may be borrowed, and destroys it otherwise.
+func (p *Pool[T]) usable(pooled pooledItem[T]) bool {
+	if p.expired(pooled) || (p.opts.Validate != nil && !p.opts.Validate(pooled.item)) {
+		p.remove(pooled.item)
+		return false
+	}
+	return true
+}
+
+func (p *Pool[T]) borrow() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.borrowed++
+}
+
+// release ends a borrow, panicking if none is outstanding.
+func (p *Pool[T]) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.borrowed == 0 {
+		panic("channerics: Pool item returned which was not borrowed")
+	}
+	p.borrowed--
+}
+
+func (p *Pool[T]) expired(pooled pooledItem[T]) bool {
+	return p.opts.IdleTimeout > 0 && p.clock.Now().Sub(pooled.since) >= p.opts.IdleTimeout
+}
+
+// Put returns a borrowed item to the pool, resetting it first. If the pool is closed
+// the item is destroyed instead. Put panics if no item is borrowed.
+func (p *Pool[T]) Put(t T) {
+	p.release()
+
+	select {
+	case <-p.closed:
+		p.remove(t)
+		return
+	default:
+	}
+
+	if p.opts.Reset != nil {
+		p.opts.Reset(t)
+	}
+
+	// Never blocks, since idle items never outnumber the live items which are not borrowed.
+	p.idle <- pooledItem[T]{item: t, since: p.clock.Now()}
+
+	// Close may have drained the idle items before t was added.
+	select {
+	case <-p.closed:
+		p.drain()
+	default:
+	}
+}
+
+// Discard destroys a borrowed item instead of returning it, e.g. because it is broken,
+// which allows another item to be created in its place. Discard panics if no item is borrowed.
+func (p *Pool[T]) Discard(t T) {
+	p.release()
+	p.remove(t)
+}
+
+// remove destroys a live item and frees its slot.
+func (p *Pool[T]) remove(t T) {
+	p.destroy(t)
+	<-p.slots
+}
+
+func (p *Pool[T]) destroy(t T) {
+	if p.opts.Destroy != nil {
+		p.opts.Destroy(t)
+	}
+}
+
+// Close closes the pool and destroys its idle items, after which Get returns ErrClosed and
+// blocked Get calls are unblocked. Borrowed items are destroyed when they are returned.
+// Calls after the first return ErrClosed.
+func (p *Pool[T]) Close() (err error) {
+	err = ErrClosed
+	p.closer.Do(func() {
+		close(p.closed)
+		// The reaper holds idle items while it checks them, so await it before draining.
+		<-p.reaped
+		p.drain()
+		err = nil
+	})
+	return
+}
+
+// drain destroys all idle items.
+func (p *Pool[T]) drain() {
+	for {
+		select {
+		case pooled := <-p.idle:
+			p.remove(pooled.item)
+		default:
+			return
+		}
+	}
+}
+
+// reap periodically destroys expired idle items until the pool is closed.
+func (p *Pool[T]) reap() {
+	defer close(p.reaped)
+
+	ticker := p.clock.NewTicker(p.opts.IdleTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.closed:
+			return
+		case <-ticker.C():
+		}
+
+		// Check only the items idle at this time, retaining their order.
+		var keep []pooledItem[T]
+		for n := len(p.idle); n > 0; n-- {
+			select {
+			case pooled := <-p.idle:
+				if p.expired(pooled) {
+					p.remove(pooled.item)
+				} else {
+					keep = append(keep, pooled)
+				}
+			default:
+				n = 0
+			}
+		}
+		for _, pooled := range keep {
+			// There is always room, since the idle items never outnumber the live ones.
+			p.idle <- pooled
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPool(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	// conn is a pooled test item.
+	type conn struct {
+		id     int
+		broken bool
+		dirty  bool
+	}
+
+	// newTestPool returns a pool of conns and a func returning the ids of destroyed conns.
+	newTestPool := func(clock Clock, maxLive int, opts PoolOptions[*conn]) (*Pool[*conn], func() []int) {
+		var mu sync.Mutex
+		var ids int
+		var destroyed []int
+
+		opts.Destroy = func(c *conn) {
+			mu.Lock()
+			defer mu.Unlock()
+			destroyed = append(destroyed, c.id)
+		}
+		pool, err := NewPool(
+			clock,
+			maxLive,
+			func(ctx context.Context) (*conn, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				ids++
+				return &conn{id: ids}, nil
+			},
+			opts,
+		)
+		So(err, ShouldBeNil)
+
+		return pool, func() []int {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]int(nil), destroyed...)
+		}
+	}
+
+	Convey("Pool tests", t, func() {
+		Convey("When we try to init a Pool of size 0", func() {
+			pool, err := NewPool(SystemClock{}, 0, func(ctx context.Context) (int, error) {
+				return 0, nil
+			}, PoolOptions[int]{})
+			So(pool, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+		})
+
+		Convey("When items are returned they are reused and reset", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 2, PoolOptions[*conn]{
+				Reset: func(c *conn) { c.dirty = false },
+			})
+			defer pool.Close()
+			ctx := context.Background()
+
+			c1, err := pool.Get(ctx)
+			So(err, ShouldBeNil)
+			So(c1.id, ShouldEqual, 1)
+			c1.dirty = true
+			pool.Put(c1)
+
+			c2, err := pool.Get(ctx)
+			So(err, ShouldBeNil)
+			So(c2, ShouldEqual, c1)
+			So(c2.dirty, ShouldBeFalse)
+			So(destroyed(), ShouldBeEmpty)
+		})
+
+		Convey("When all items are borrowed Get blocks until one is returned", func() {
+			pool, _ := newTestPool(SystemClock{}, 1, PoolOptions[*conn]{})
+			defer pool.Close()
+
+			c1, err := pool.Get(context.Background())
+			So(err, ShouldBeNil)
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), time.Duration(10)*time.Millisecond)
+			defer cancelFn()
+			_, err = pool.Get(ctx)
+			So(err, ShouldBeError, context.DeadlineExceeded)
+			// Cancellation has precedence, even once an item is idle.
+			_, err = pool.Get(ctx)
+			So(err, ShouldBeError, context.DeadlineExceeded)
+
+			got := make(chan *conn)
+			go func() {
+				c, _ := pool.Get(context.Background())
+				got <- c
+			}()
+			// Let Get block, such that the item is received while it awaits one.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			pool.Put(c1)
+			select {
+			case c := <-got:
+				So(c, ShouldEqual, c1)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When a borrowed item is discarded another may be created", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 1, PoolOptions[*conn]{})
+			defer pool.Close()
+			ctx := context.Background()
+
+			c1, _ := pool.Get(ctx)
+			pool.Discard(c1)
+			So(destroyed(), ShouldResemble, []int{1})
+
+			c2, err := pool.Get(ctx)
+			So(err, ShouldBeNil)
+			So(c2.id, ShouldEqual, 2)
+		})
+
+		Convey("When idle items fail validation they are destroyed and replaced", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 1, PoolOptions[*conn]{
+				Validate: func(c *conn) bool { return !c.broken },
+			})
+			defer pool.Close()
+			ctx := context.Background()
+
+			c1, _ := pool.Get(ctx)
+			c1.broken = true
+			pool.Put(c1)
+
+			c2, err := pool.Get(ctx)
+			So(err, ShouldBeNil)
+			So(c2.id, ShouldEqual, 2)
+			So(destroyed(), ShouldResemble, []int{1})
+
+			Convey("Including those returned while Get is blocked", func() {
+				got := make(chan *conn)
+				go func() {
+					c, _ := pool.Get(ctx)
+					got <- c
+				}()
+				time.Sleep(time.Duration(25) * time.Millisecond)
+				c2.broken = true
+				pool.Put(c2)
+				select {
+				case c := <-got:
+					So(c.id, ShouldEqual, 3)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+				So(destroyed(), ShouldResemble, []int{1, 2})
+			})
+		})
+
+		Convey("When create fails its slot is released", func() {
+			fail := true
+			errCreate := errors.New("create failed")
+			pool, err := NewPool(SystemClock{}, 1, func(ctx context.Context) (int, error) {
+				if fail {
+					return 0, errCreate
+				}
+				return 42, nil
+			}, PoolOptions[int]{})
+			So(err, ShouldBeNil)
+			defer pool.Close()
+
+			_, err = pool.Get(context.Background())
+			So(err, ShouldBeError, errCreate)
+			fail = false
+			item, err := pool.Get(context.Background())
+			So(err, ShouldBeNil)
+			So(item, ShouldEqual, 42)
+		})
+
+		Convey("When idle items expire they are destroyed", func() {
+			clock := NewFakeClock(epoch)
+			idleTimeout := time.Minute
+			pool, destroyed := newTestPool(clock, 2, PoolOptions[*conn]{
+				IdleTimeout: idleTimeout,
+			})
+			defer pool.Close()
+			ctx := context.Background()
+			// Await the reaper's ticker.
+			clock.BlockUntil(1)
+
+			c1, _ := pool.Get(ctx)
+			c2, _ := pool.Get(ctx)
+			pool.Put(c1)
+			clock.Advance(idleTimeout / 2)
+			pool.Put(c2)
+
+			// The reaper destroys c1 upon its tick, but c2 has only been idle half as long.
+			clock.Advance(idleTimeout / 2)
+			for i := 0; i < 250 && len(destroyed()) == 0; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			So(destroyed(), ShouldResemble, []int{1})
+
+			// An expired item is also destroyed upon Get, regardless of the reaper.
+			clock.Advance(idleTimeout / 2)
+			c3, err := pool.Get(ctx)
+			So(err, ShouldBeNil)
+			So(c3.id, ShouldEqual, 3)
+			So(destroyed(), ShouldResemble, []int{1, 2})
+		})
+
+		Convey("When the pool is closed its idle items are destroyed", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 2, PoolOptions[*conn]{})
+			ctx := context.Background()
+
+			c1, _ := pool.Get(ctx)
+			pool.Put(c1)
+			So(pool.Close(), ShouldBeNil)
+			So(pool.Close(), ShouldBeError, ErrClosed)
+			So(destroyed(), ShouldResemble, []int{1})
+
+			_, err := pool.Get(ctx)
+			So(err, ShouldBeError, ErrClosed)
+		})
+
+		Convey("When the pool is closed blocked borrowers are unblocked", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 1, PoolOptions[*conn]{})
+			ctx := context.Background()
+			c1, _ := pool.Get(ctx)
+
+			blocked := make(chan error)
+			go func() {
+				_, err := pool.Get(ctx)
+				blocked <- err
+			}()
+
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			So(pool.Close(), ShouldBeNil)
+			select {
+			case err := <-blocked:
+				So(err, ShouldBeError, ErrClosed)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			// Borrowed items are destroyed upon return.
+			So(destroyed(), ShouldBeEmpty)
+			pool.Put(c1)
+			So(destroyed(), ShouldResemble, []int{1})
+		})
+
+		Convey("When the pool is closed while an item is returned the item is destroyed", func() {
+			var pool *Pool[*conn]
+			pool, destroyed := newTestPool(SystemClock{}, 1, PoolOptions[*conn]{
+				// Close after Put has checked for closure, but before the item is idle.
+				Reset: func(c *conn) { pool.Close() },
+			})
+			c1, _ := pool.Get(context.Background())
+			pool.Put(c1)
+			So(destroyed(), ShouldResemble, []int{1})
+			_, err := pool.Get(context.Background())
+			So(err, ShouldBeError, ErrClosed)
+		})
+
+		Convey("When an item is returned twice Put panics instead of pooling it twice", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 2, PoolOptions[*conn]{})
+			defer pool.Close()
+			c1, _ := pool.Get(context.Background())
+			pool.Put(c1)
+
+			So(func() { pool.Put(c1) }, ShouldPanic)
+			So(func() { pool.Discard(c1) }, ShouldPanic)
+			c2, err := pool.Get(context.Background())
+			So(err, ShouldBeNil)
+			So(c2, ShouldEqual, c1)
+			c3, err := pool.Get(context.Background())
+			So(err, ShouldBeNil)
+			So(c3, ShouldNotEqual, c1)
+			So(destroyed(), ShouldBeEmpty)
+		})
+
+		Convey("When an item which was not borrowed is returned after Close Put panics", func() {
+			pool, destroyed := newTestPool(SystemClock{}, 1, PoolOptions[*conn]{})
+			So(pool.Close(), ShouldBeNil)
+
+			So(func() { pool.Put(&conn{id: 42}) }, ShouldPanic)
+			So(func() { pool.Discard(&conn{id: 42}) }, ShouldPanic)
+			So(destroyed(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 Jesse Waite
+
+// scope.go is for structured concurrency: a Scope owns a done channel and the goroutines
+// started within it, including those of combinators started in it, such that a caller can
+// await all of them and a failed goroutine cancels its siblings.
+
+package channerics
+
+import (
+	"sync"
+	"time"
+)
+
+// spawner starts a goroutine on behalf of a combinator, such that it may be tracked.
+type spawner func(f func())
+
+// goAsync is the spawner of the untracked combinators.
+func goAsync(f func()) {
+	go f()
+}
+
+// Scope owns a done channel and every goroutine started via Go, or by a combinator
+// started in it, such as MergeIn. Wait returns once all of them have exited. The first
+// goroutine to return an error cancels the scope, and so its siblings.
+// Nested scopes created by Child are cancelled with their parent, and their goroutines
+// are awaited by their parent's Wait. The zero value is not usable; use NewScope.
+type Scope struct {
+	done   *Done
+	parent *Scope
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewScope returns a Scope which is cancelled when parent is closed. Its resources are
+// released once Wait returns, or once parent is closed.
+func NewScope(parent <-chan struct{}) *Scope {
+	s := &Scope{
+		done: NewDone(),
+	}
+
+	go func() {
+		select {
+		case <-parent:
+			s.done.Cancel(nil)
+		case <-s.done.Done():
+		}
+	}()
+
+	return s
+}
+
+// Child returns a nested Scope which is cancelled when s is, and whose goroutines are also
+// awaited by s.Wait. Errors in the child only cancel the child, and are returned by its Wait.
+func (s *Scope) Child() *Scope {
+	return &Scope{
+		done:   s.done.Child(),
+		parent: s,
+	}
+}
+
+// Done returns the scope's done channel, which is closed when the scope is cancelled.
+func (s *Scope) Done() <-chan struct{} {
+	return s.done.Done()
+}
+
+// Cancel cancels the scope and its nested scopes, recording err as the cause per Done.Cancel.
+func (s *Scope) Cancel(err error) {
+	s.done.Cancel(err)
+}
+
+// Go runs fn in a new goroutine owned by the scope, passing it the scope's done channel.
+// If fn returns an error and is the first in the scope to do so, the scope is cancelled
+// with the error as its cause, and the error is returned by Wait.
+func (s *Scope) Go(fn func(done <-chan struct{}) error) {
+	s.spawn(func() {
+		if err := fn(s.Done()); err != nil {
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.mu.Unlock()
+			s.done.Cancel(err)
+		}
+	})
+}
+
+// spawn runs f in a new goroutine awaited by the scope and its ancestors.
+func (s *Scope) spawn(f func()) {
+	for scope := s; scope != nil; scope = scope.parent {
+		scope.wg.Add(1)
+	}
+
+	go func() {
+		defer func() {
+			for scope := s; scope != nil; scope = scope.parent {
+				scope.wg.Done()
+			}
+		}()
+		f()
+	}()
+}
+
+// Wait blocks until every goroutine owned by the scope, including those of its nested
+// scopes, has exited. It then cancels the scope and returns the first error returned by
+// a goroutine started via Go, or nil.
+func (s *Scope) Wait() error {
+	s.wg.Wait()
+	s.done.Cancel(nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// The following combinators are started in a scope, which owns their internal goroutines
+// and whose done channel stops them; see their unscoped counterparts for details.
+// Steward has no scoped variant, since it does not await its wards, which may be hung.
+
+// OrDoneIn is OrDone started in scope s.
+func OrDoneIn[T any](s *Scope, vals <-chan T) <-chan T {
+	return orDone(s.spawn, s.Done(), vals)
+}
+
+// MergeIn is Merge started in scope s.
+func MergeIn[T any](s *Scope, inputs ...<-chan T) <-chan T {
+	return merge(s.spawn, s.Done(), inputs...)
+}
+
+// BroadcastIn is Broadcast started in scope s.
+func BroadcastIn[T any](s *Scope, input <-chan T, n int) []<-chan T {
+	return broadcast(s.spawn, s.Done(), input, n)
+}
+
+// TeeIn is Tee started in scope s.
+func TeeIn[T any](s *Scope, in <-chan T) (<-chan T, <-chan T) {
+	return tee(s.spawn, s.Done(), in)
+}
+
+// GeneratorIn is Generator started in scope s.
+func GeneratorIn[T any](s *Scope, generate func() (T, bool)) <-chan T {
+	return generator(s.spawn, s.Done(), generate)
+}
+
+// RepeaterIn is Repeater started in scope s.
+func RepeaterIn[T any](s *Scope, seq []T) <-chan T {
+	return repeater(s.spawn, s.Done(), seq)
+}
+
+// ConvertIn is Convert started in scope s.
+func ConvertIn[T1 any, T2 any](s *Scope, vals <-chan T1, convertFn func(T1) T2) <-chan T2 {
+	return convert(s.spawn, s.Done(), vals, convertFn)
+}
+
+// AsTypeIn is AsType started in scope s.
+func AsTypeIn[T any](s *Scope, vals chan interface{}) <-chan T {
+	return asType[T](s.spawn, s.Done(), vals)
+}
+
+// BridgeIn is Bridge started in scope s.
+func BridgeIn[T any](s *Scope, chanStream <-chan <-chan T) <-chan T {
+	return bridge(s.spawn, s.Done(), chanStream)
+}
+
+// ConcatIn is Concat started in scope s.
+func ConcatIn[T any](s *Scope, chans ...<-chan T) <-chan T {
+	return concat(s.spawn, s.Done(), chans...)
+}
+
+// FanOutIn is FanOut started in scope s.
+func FanOutIn[T any](s *Scope, in <-chan T, n int, strategy FanOutStrategy[T]) []<-chan T {
+	return fanOut(s.spawn, s.Done(), in, n, strategy)
+}
+
+// BatchIn is Batch started in scope s.
+func BatchIn[T any](s *Scope, clock Clock, in <-chan T, maxSize int, maxWait time.Duration) <-chan []T {
+	return batch(s.spawn, s.Done(), clock, in, maxSize, maxWait)
+}
+
+// NewTickerIn is NewTickerWithClock started in scope s.
+func NewTickerIn(s *Scope, clock Clock, duration time.Duration) chan time.Time {
+	return newTicker(s.spawn, s.Done(), clock, duration)
+}
+
+// ThrottleIn is Throttle started in scope s, which also owns its token bucket's goroutines.
+func ThrottleIn[T any](s *Scope, clock Clock, in <-chan T, rate float64, burst int) (<-chan T, error) {
+	return throttle(s.spawn, s.Done(), clock, in, rate, burst)
+}
+
+// DebounceIn is Debounce started in scope s.
+func DebounceIn[T any](s *Scope, clock Clock, in <-chan T, quiet time.Duration) <-chan T {
+	return debounce(s.spawn, s.Done(), clock, in, quiet)
+}
+
+// SampleIn is Sample started in scope s.
+func SampleIn[T any](s *Scope, clock Clock, in <-chan T, interval time.Duration) <-chan T {
+	return sample(s.spawn, s.Done(), clock, in, interval)
+}
+
+// WithHeartbeatIn is WithHeartbeat started in scope s, which also owns the goroutine
+// running work.
+func WithHeartbeatIn(
+	s *Scope,
+	clock Clock,
+	interval time.Duration,
+	work func(done <-chan struct{}, pulse func()),
+) <-chan struct{} {
+	return withHeartbeat(s.spawn, s.Done(), clock, interval, work)
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScope(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	errFailed := errors.New("failed")
+	epoch := time.Unix(0, 0)
+
+	// waitOrTimeout returns the result of s.Wait, failing if it does not return promptly.
+	waitOrTimeout := func(s *Scope) error {
+		result := make(chan error)
+		go func() {
+			result <- s.Wait()
+		}()
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(maxWaitForEffect):
+			t.FailNow()
+		}
+		return nil
+	}
+
+	Convey("Scope tests", t, func() {
+		Convey("When all goroutines succeed Wait awaits them and returns nil", func() {
+			s := NewScope(nil)
+			var exited int32
+			for i := 0; i < 3; i++ {
+				s.Go(func(done <-chan struct{}) error {
+					time.Sleep(time.Duration(5) * time.Millisecond)
+					atomic.AddInt32(&exited, 1)
+					return nil
+				})
+			}
+
+			So(waitOrTimeout(s), ShouldBeNil)
+			So(atomic.LoadInt32(&exited), ShouldEqual, 3)
+			// The scope is cancelled once Wait returns, releasing its resources.
+			So(s.done.Err(), ShouldNotBeNil)
+		})
+
+		Convey("When a goroutine fails its siblings are cancelled", func() {
+			s := NewScope(nil)
+			s.Go(func(done <-chan struct{}) error {
+				<-done
+				return nil
+			})
+			s.Go(func(done <-chan struct{}) error {
+				return errFailed
+			})
+
+			So(waitOrTimeout(s), ShouldEqual, errFailed)
+			So(s.done.Err(), ShouldEqual, errFailed)
+		})
+
+		Convey("When the parent done channel is closed the scope is cancelled", func() {
+			parent := make(chan struct{})
+			s := NewScope(parent)
+			s.Go(func(done <-chan struct{}) error {
+				<-done
+				return nil
+			})
+
+			close(parent)
+			So(waitOrTimeout(s), ShouldBeNil)
+		})
+
+		Convey("When a scope is nested", func() {
+			s := NewScope(nil)
+			child := s.Child()
+
+			var childExited int32
+			child.Go(func(done <-chan struct{}) error {
+				<-done
+				atomic.StoreInt32(&childExited, 1)
+				return nil
+			})
+
+			Convey("The parent's cancellation cancels the child, and awaits its goroutines", func() {
+				s.Cancel(errFailed)
+				So(waitOrTimeout(s), ShouldBeNil)
+				So(atomic.LoadInt32(&childExited), ShouldEqual, 1)
+				So(child.done.Err(), ShouldEqual, errFailed)
+			})
+
+			Convey("The child's failure does not cancel the parent", func() {
+				child.Go(func(done <-chan struct{}) error {
+					return errFailed
+				})
+				So(waitOrTimeout(child), ShouldEqual, errFailed)
+				select {
+				case <-s.Done():
+					t.FailNow()
+				default:
+				}
+				So(waitOrTimeout(s), ShouldBeNil)
+			})
+		})
+
+		Convey("When combinators are started in a scope Wait awaits their goroutines", func() {
+			s := NewScope(nil)
+			in1 := make(chan int)
+			in2 := make(chan int)
+
+			merged := MergeIn(s, in1, in2)
+			outs := BroadcastIn(s, merged, 2)
+			out1, out2 := TeeIn(s, OrDoneIn(s, outs[0]))
+			converted := ConvertIn(s, out1, func(i int) int { return i * 2 })
+
+			s.Go(func(done <-chan struct{}) error {
+				in1 <- 1
+				return nil
+			})
+			So(<-converted, ShouldEqual, 2)
+			So(<-out2, ShouldEqual, 1)
+			So(<-outs[1], ShouldEqual, 1)
+
+			// Cancelling the scope stops every combinator, which Wait observes.
+			s.Cancel(nil)
+			So(waitOrTimeout(s), ShouldBeNil)
+			for _, ch := range []<-chan int{merged, outs[1], out2, converted} {
+				_, ok := <-ch
+				So(ok, ShouldBeFalse)
+			}
+		})
+
+		Convey("When sequences are started in a scope", func() {
+			s := NewScope(nil)
+			i := 0
+			gen := GeneratorIn(s, func() (int, bool) {
+				i++
+				return i, i <= 2
+			})
+			So(<-gen, ShouldEqual, 1)
+			So(<-gen, ShouldEqual, 2)
+
+			rep := RepeaterIn(s, []string{"a"})
+			So(<-rep, ShouldEqual, "a")
+
+			vals := make(chan interface{}, 1)
+			vals <- "b"
+			typed := AsTypeIn[string](s, vals)
+			So(<-typed, ShouldEqual, "b")
+
+			s.Cancel(nil)
+			So(waitOrTimeout(s), ShouldBeNil)
+		})
+
+		Convey("When streams are reshaped in a scope", func() {
+			s := NewScope(nil)
+			in := make(chan int, 4)
+			for i := 1; i <= 4; i++ {
+				in <- i
+			}
+			close(in)
+
+			outs := FanOutIn(s, in, 2, RoundRobin[int]())
+			So(outs, ShouldHaveLength, 2)
+			concatenated := ConcatIn(s, outs[0], outs[1])
+			So(<-concatenated, ShouldEqual, 1)
+
+			chanStream := make(chan (<-chan int), 1)
+			chanStream <- outs[1]
+			bridged := BridgeIn(s, chanStream)
+			So(<-bridged, ShouldEqual, 2)
+
+			batches := BatchIn(s, NewFakeClock(epoch), concatenated, 1, 0)
+			So(<-batches, ShouldResemble, []int{3})
+
+			s.Cancel(nil)
+			So(waitOrTimeout(s), ShouldBeNil)
+		})
+
+		Convey("When timed combinators are started in a scope Wait awaits their goroutines", func() {
+			s := NewScope(nil)
+			clock := NewFakeClock(epoch)
+			in := make(chan int)
+
+			throttled, err := ThrottleIn(s, clock, in, 1, 1)
+			So(err, ShouldBeNil)
+			debounced := DebounceIn(s, clock, in, time.Second)
+			sampled := SampleIn(s, clock, in, time.Second)
+			ticks := NewTickerIn(s, clock, time.Second)
+			heartbeat := WithHeartbeatIn(s, clock, time.Second, func(done <-chan struct{}, pulse func()) {
+				pulse()
+				<-done
+			})
+			_, ok := <-heartbeat
+			So(ok, ShouldBeTrue)
+
+			_, err = ThrottleIn(s, clock, in, 0, 1)
+			So(err, ShouldEqual, ErrInvalidRate)
+
+			s.Cancel(nil)
+			So(waitOrTimeout(s), ShouldBeNil)
+			for _, ch := range []<-chan int{throttled, debounced, sampled} {
+				_, ok := <-ch
+				So(ok, ShouldBeFalse)
+			}
+			_, ok = <-ticks
+			So(ok, ShouldBeFalse)
+			for range heartbeat {
+			}
+		})
+	})
+}
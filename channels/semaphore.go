@@ -5,23 +5,40 @@
 package channerics
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrClosed        = errors.New("closed")
+	ErrWouldBlock    = errors.New("operation would block")
+	ErrDoubleRelease = errors.New("permit already released")
 )
 
 // Semaphore represents semaphore turnstile semantics implemented using a buffered channel.
 // This is traditionally used for finite concurrency limits, such as n concurrent db connections.
 type Semaphore struct {
-	ch          chan struct{}
+	ch chan struct{}
+	// closed is closed by Close; ch itself is never closed, since a send on a closed
+	// channel would panic.
+	closed      chan struct{}
 	safe_closer sync.Once
 }
 
-// TODO: see sync/semaphore weighted-semaphore implementation. This
-// implementation likely not needed; the stdlib weight version likely has this covered.
+// Permit is a single unit of a Semaphore, returned by Take and MaybeTake.
+// A Permit must be released exactly once.
+type Permit struct {
+	sem      *Semaphore
+	released int32
+}
 
 // NewSemaphore returns a context-sensitive semaphore built on a buffered chan.
 // If n == 0, use sync.Mutex or sync.RWMutex instead.
 // Also note that an idiomatic local semaphore can be written simply:
+//
 //	go func() {
 //		sem := make(chan struct{}, 10)
 //		defer close(sem)
@@ -37,25 +54,36 @@ type Semaphore struct {
 //			<-ch
 //		}
 //	}
-//
 func NewSemaphore(n int) *Semaphore {
 	return &Semaphore{
 		ch:          make(chan struct{}, n),
+		closed:      make(chan struct{}),
 		safe_closer: sync.Once{},
 	}
 }
 
 // Take 'decrements' the semaphore, blocking until the semaphore is available.
-// Taken indicates that take succeeded without context cancellation; if true,
-// then Release must be called later, e.g. using 'defer sem.Release()'.
-func (sem *Semaphore) Take(ctx context.Context) (taken bool) {
+// On success the returned Permit must be released later, e.g. using 'defer permit.Release()'.
+// Take returns ctx.Err() if ctx is cancelled, or ErrClosed if the semaphore is closed,
+// before the semaphore is available.
+func (sem *Semaphore) Take(ctx context.Context) (*Permit, error) {
+	// Done-guard: closure and cancellation have precedence over an available semaphore.
 	select {
-	case sem.ch <- struct{}{}:
-		taken = true
+	case <-sem.closed:
+		return nil, ErrClosed
 	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
 
-	return
+	select {
+	case sem.ch <- struct{}{}:
+		return &Permit{sem: sem}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-sem.closed:
+		return nil, ErrClosed
+	}
 }
 
 // UnsafeCount returns an immediately expired view of the semaphore count,
@@ -67,31 +95,187 @@ func (sem *Semaphore) UnsafeCount() int {
 	return cap(sem.ch) - len(sem.ch)
 }
 
-// MaybeTake attempts to take (decrement) if it would not block, otherwise returns immediately.
-// MaybeTake does not take a context since it is non-blocking.
-// Taken indicates that take succeeded without context cancellation; if true,
-// then Release must be called later, e.g. using 'defer sem.Release()'.
-func (sem *Semaphore) MaybeTake() (taken bool) {
+// MaybeTake attempts to take (decrement) if it would not block, otherwise returns immediately
+// with ErrWouldBlock. MaybeTake does not take a context since it is non-blocking.
+// On success the returned Permit must be released later, e.g. using 'defer permit.Release()'.
+// MaybeTake returns ErrClosed if the semaphore is closed.
+func (sem *Semaphore) MaybeTake() (*Permit, error) {
+	select {
+	case <-sem.closed:
+		return nil, ErrClosed
+	default:
+	}
+
 	select {
 	case sem.ch <- struct{}{}:
-		taken = true
+		return &Permit{sem: sem}, nil
+	default:
+		return nil, ErrWouldBlock
+	}
+}
+
+// Release increments the semaphore without blocking. Release is idempotent: only the first
+// call has an effect, and subsequent calls report the misuse by returning ErrDoubleRelease.
+// Release returns ErrClosed if the semaphore has been closed.
+func (p *Permit) Release() error {
+	if !atomic.CompareAndSwapInt32(&p.released, 0, 1) {
+		return ErrDoubleRelease
+	}
+
+	select {
+	case <-p.sem.closed:
+		return ErrClosed
 	default:
 	}
 
+	// The permit's slot is guaranteed to be occupied, so this never blocks.
+	<-p.sem.ch
+	return nil
+}
+
+// Close closes the semaphore, after which other methods return ErrClosed and
+// blocked Take calls are unblocked. Close may be called repeatedly from multiple
+// go routines; calls after the first return ErrClosed.
+func (sem *Semaphore) Close() (err error) {
+	err = ErrClosed
+	sem.safe_closer.Do(func() {
+		close(sem.closed)
+		err = nil
+	})
 	return
 }
 
-// Release increments the semaphore without blocking and must be called after calling
-// Take(), its counterpart.
-func (sem *Semaphore) Release() {
+// WeightedSemaphore is a counting semaphore whose permits are taken and released in
+// arbitrary weights, such as a memory budget shared by jobs of different sizes.
+// Waiters are served in strict FIFO order: a large request at the head of the queue blocks
+// subsequent smaller requests until it is satisfied, so that it is never starved by them.
+type WeightedSemaphore struct {
+	size    int
+	cur     int
+	mu      sync.Mutex
+	waiters list.List
+}
+
+// weightedWaiter is a pending TakeN call; ready is closed once its permits are granted.
+type weightedWaiter struct {
+	n     int
+	ready chan struct{}
+}
+
+// NewWeightedSemaphore returns a weighted semaphore with n permits.
+func NewWeightedSemaphore(n int) *WeightedSemaphore {
+	return &WeightedSemaphore{
+		size: n,
+	}
+}
+
+// TakeN takes n permits, blocking until they are available and every earlier waiter has been
+// served, or until ctx is cancelled. Taken indicates that the permits were taken; if true,
+// then ReleaseN(n) must be called later. TakeN immediately returns false if n exceeds the
+// semaphore's size, since the request could never be satisfied, or if n is negative.
+// Taking zero permits always succeeds without waiting, unless ctx is already cancelled.
+// As with other functions in this package, cancellation has precedence: if ctx is cancelled
+// concurrently with the permits being granted, they are returned and TakeN returns false.
+func (sem *WeightedSemaphore) TakeN(ctx context.Context, n int) (taken bool) {
+	// Done-guard: cancellation has precedence over available permits.
 	select {
-	case <-sem.ch:
+	case <-ctx.Done():
+		return false
 	default:
 	}
+
+	if n < 0 {
+		return false
+	}
+	if n == 0 {
+		// Zero permits cannot delay, nor be delayed by, any waiter.
+		return true
+	}
+
+	sem.mu.Lock()
+	if n > sem.size {
+		sem.mu.Unlock()
+		return false
+	}
+	if sem.size-sem.cur >= n && sem.waiters.Len() == 0 {
+		sem.cur += n
+		sem.mu.Unlock()
+		return true
+	}
+
+	w := &weightedWaiter{n: n, ready: make(chan struct{})}
+	elem := sem.waiters.PushBack(w)
+	sem.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+		sem.mu.Lock()
+		defer sem.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted concurrently with cancellation; give the permits back.
+			sem.cur -= n
+		default:
+			sem.waiters.Remove(elem)
+		}
+		// Removing a waiter, or returning its permits, may unblock those behind it.
+		sem.notifyWaiters()
+		return false
+	}
 }
 
-// Close closes the semaphore's channel, after which other methods will panic if called.
-// Close may be called repeatedly from multiple go routines.
-func (sem *Semaphore) Close() {
-	sem.safe_closer.Do(func() { close(sem.ch) })
+// TryTakeN takes n permits if doing so would not block, and returns whether they were taken.
+// Permits are never taken ahead of waiters, per the FIFO guarantee. As with TakeN, negative
+// n is rejected and taking zero permits always succeeds.
+func (sem *WeightedSemaphore) TryTakeN(n int) (taken bool) {
+	if n <= 0 {
+		return n == 0
+	}
+
+	sem.mu.Lock()
+	defer sem.mu.Unlock()
+
+	if sem.size-sem.cur >= n && sem.waiters.Len() == 0 {
+		sem.cur += n
+		return true
+	}
+	return false
+}
+
+// ReleaseN returns n permits to the semaphore and unblocks waiters in FIFO order.
+// ReleaseN panics if n is negative or more permits are released than are held, since these
+// are always bugs. Releasing zero permits has no effect.
+func (sem *WeightedSemaphore) ReleaseN(n int) {
+	if n < 0 {
+		panic("channerics: WeightedSemaphore released a negative number of permits")
+	}
+	if n == 0 {
+		return
+	}
+
+	sem.mu.Lock()
+	defer sem.mu.Unlock()
+
+	if n > sem.cur {
+		panic("channerics: WeightedSemaphore released more permits than held")
+	}
+	sem.cur -= n
+	sem.notifyWaiters()
+}
+
+// notifyWaiters grants permits to waiters from the head of the queue until the head waiter
+// does not fit; callers must hold the lock.
+func (sem *WeightedSemaphore) notifyWaiters() {
+	for elem := sem.waiters.Front(); elem != nil; elem = sem.waiters.Front() {
+		w := elem.Value.(*weightedWaiter)
+		if sem.size-sem.cur < w.n {
+			// Strict FIFO: smaller waiters behind w must not starve it.
+			return
+		}
+		sem.cur += w.n
+		sem.waiters.Remove(elem)
+		close(w.ready)
+	}
 }
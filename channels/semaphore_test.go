@@ -24,22 +24,23 @@ func TestSemaphore(t *testing.T) {
 			sem := NewSemaphore(2)
 			defer sem.Close()
 			ctx := context.Background()
-			taken := sem.Take(ctx)
-			So(taken, ShouldBeTrue)
-			taken = sem.Take(ctx)
-			So(taken, ShouldBeTrue)
+			permit, err := sem.Take(ctx)
+			So(err, ShouldBeNil)
+			So(permit, ShouldNotBeNil)
+			_, err = sem.Take(ctx)
+			So(err, ShouldBeNil)
 
 			// Count is zero, now we should block.
 			blocked := make(chan struct{})
 			isBlocked := false
-			taken = false
+			var blockedPermit *Permit
 
 			// Wait until the Take() is scheduled
 			var wg sync.WaitGroup
 			wg.Add(1)
 			go func() {
 				wg.Done()
-				taken = sem.Take(ctx)
+				blockedPermit, _ = sem.Take(ctx)
 				close(blocked)
 			}()
 			wg.Wait()
@@ -51,10 +52,9 @@ func TestSemaphore(t *testing.T) {
 			}
 
 			So(isBlocked, ShouldBeTrue)
-			So(taken, ShouldBeFalse)
 
 			// Release the semaphore; since there are no other users of it, Take() should be unblocked.
-			sem.Release()
+			So(permit.Release(), ShouldBeNil)
 			// Wait or timeout for Take() to become unblocked
 			select {
 			case _, isBlocked = <-blocked:
@@ -62,7 +62,7 @@ func TestSemaphore(t *testing.T) {
 			}
 
 			So(isBlocked, ShouldBeFalse)
-			So(taken, ShouldBeTrue)
+			So(blockedPermit, ShouldNotBeNil)
 		})
 
 		Convey("When cancelled before Take", func() {
@@ -72,14 +72,14 @@ func TestSemaphore(t *testing.T) {
 			// Cancel before Take()
 			cancelFn()
 
-			taken := false
+			var err error
 			runner := func() {
-				taken = sem.Take(ctx)
+				_, err = sem.Take(ctx)
 			}
 			didComplete := RunOrTimeout(runner, 250)
 
 			So(didComplete, ShouldBeFalse)
-			So(taken, ShouldBeFalse)
+			So(err, ShouldBeError, context.Canceled)
 		})
 
 		Convey("When cancelled after Take", func() {
@@ -87,20 +87,18 @@ func TestSemaphore(t *testing.T) {
 			defer sem.Close()
 
 			ctx, cancelFn := context.WithCancel(context.Background())
-			takeResult := true
+			var takeErr error
 			takeCompleted := make(chan struct{})
 			var wg sync.WaitGroup
 			wg.Add(1)
 			go func() {
 				wg.Done()
-				takeResult = sem.Take(ctx)
+				_, takeErr = sem.Take(ctx)
 				close(takeCompleted)
 			}()
 			// Wait until routine is running...
 			wg.Wait()
 
-			So(takeResult, ShouldBeTrue)
-
 			// Cancel and wait until Take returns or timeout occurs.
 			cancelFn()
 			timedOut := false
@@ -111,7 +109,7 @@ func TestSemaphore(t *testing.T) {
 			}
 
 			So(timedOut, ShouldBeFalse)
-			So(takeResult, ShouldBeFalse)
+			So(takeErr, ShouldBeError, context.Canceled)
 		})
 
 		Convey("When UnsafeCount is called", func() {
@@ -120,8 +118,8 @@ func TestSemaphore(t *testing.T) {
 			defer sem.Close()
 
 			So(sem.UnsafeCount(), ShouldEqual, 10)
-			taken := sem.Take(context.Background())
-			So(taken, ShouldBeTrue)
+			_, err := sem.Take(context.Background())
+			So(err, ShouldBeNil)
 			So(sem.UnsafeCount(), ShouldEqual, 9)
 		})
 
@@ -132,13 +130,13 @@ func TestSemaphore(t *testing.T) {
 			defer sem.Close()
 
 			So(sem.UnsafeCount(), ShouldEqual, 10)
-			taken := sem.Take(context.Background())
-			So(taken, ShouldBeTrue)
+			permit, err := sem.Take(context.Background())
+			So(err, ShouldBeNil)
 			So(sem.UnsafeCount(), ShouldEqual, 9)
-			sem.Release()
+			So(permit.Release(), ShouldBeNil)
 			So(sem.UnsafeCount(), ShouldEqual, 10)
-			// Ensure releasing again after reaching capacity has no effect.
-			sem.Release()
+			// Ensure releasing again reports misuse and has no effect.
+			So(permit.Release(), ShouldBeError, ErrDoubleRelease)
 			So(sem.UnsafeCount(), ShouldEqual, 10)
 		})
 
@@ -149,16 +147,191 @@ func TestSemaphore(t *testing.T) {
 			defer sem.Close()
 
 			for i := 0; i < 10; i++ {
-				taken := sem.MaybeTake()
-				So(taken, ShouldBeTrue)
+				permit, err := sem.MaybeTake()
+				So(err, ShouldBeNil)
+				So(permit, ShouldNotBeNil)
 			}
 
 			So(sem.UnsafeCount(), ShouldEqual, 0)
-			So(sem.MaybeTake(), ShouldBeFalse)
+			permit, err := sem.MaybeTake()
+			So(permit, ShouldBeNil)
+			So(err, ShouldBeError, ErrWouldBlock)
 			So(sem.UnsafeCount(), ShouldEqual, 0)
+		})
+
+		Convey("When the semaphore is closed", func() {
+			sem := NewSemaphore(1)
+			permit, err := sem.Take(context.Background())
+			So(err, ShouldBeNil)
+
+			// A blocked Take is unblocked by Close.
+			takeErr := make(chan error)
+			go func() {
+				_, err := sem.Take(context.Background())
+				takeErr <- err
+			}()
+
+			So(sem.Close(), ShouldBeNil)
+			select {
+			case err = <-takeErr:
+				So(err, ShouldBeError, ErrClosed)
+			case <-time.After(time.Duration(250) * time.Millisecond):
+				t.FailNow()
+			}
+
+			So(sem.Close(), ShouldBeError, ErrClosed)
+			_, err = sem.Take(context.Background())
+			So(err, ShouldBeError, ErrClosed)
+			_, err = sem.MaybeTake()
+			So(err, ShouldBeError, ErrClosed)
+			So(permit.Release(), ShouldBeError, ErrClosed)
+		})
+
+	})
+}
+
+func TestWeightedSemaphore(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	// takeAsync calls TakeN in a new goroutine and returns a channel yielding its result.
+	takeAsync := func(ctx context.Context, sem *WeightedSemaphore, n int) <-chan bool {
+		result := make(chan bool, 1)
+		go func() {
+			result <- sem.TakeN(ctx, n)
+		}()
+		return result
+	}
+
+	// awaitWaiters waits until n TakeN calls are queued.
+	awaitWaiters := func(sem *WeightedSemaphore, n int) {
+		for i := 0; i < 250; i++ {
+			sem.mu.Lock()
+			queued := sem.waiters.Len()
+			sem.mu.Unlock()
+			if queued >= n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.FailNow()
+	}
+
+	Convey("WeightedSemaphore tests", t, func() {
+		Convey("When TakeN and TryTakeN are called within capacity", func() {
+			sem := NewWeightedSemaphore(10)
+			So(sem.TakeN(context.Background(), 6), ShouldBeTrue)
+			So(sem.TryTakeN(4), ShouldBeTrue)
+			So(sem.TryTakeN(1), ShouldBeFalse)
+
+			sem.ReleaseN(5)
+			So(sem.TryTakeN(5), ShouldBeTrue)
+		})
+
+		Convey("When more permits are requested than the size", func() {
+			sem := NewWeightedSemaphore(3)
+			So(sem.TakeN(context.Background(), 4), ShouldBeFalse)
+			So(sem.TryTakeN(4), ShouldBeFalse)
+		})
+
+		Convey("When n is negative or zero", func() {
+			sem := NewWeightedSemaphore(3)
+			So(sem.TakeN(context.Background(), -1), ShouldBeFalse)
+			So(sem.TryTakeN(-1), ShouldBeFalse)
+			So(func() { sem.ReleaseN(-1) }, ShouldPanic)
+
+			// Zero permits are granted even while the semaphore is exhausted and has waiters.
+			So(sem.TryTakeN(3), ShouldBeTrue)
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+			waited := make(chan bool)
+			go func() {
+				waited <- sem.TakeN(ctx, 1)
+			}()
+			So(sem.TakeN(context.Background(), 0), ShouldBeTrue)
+			So(sem.TryTakeN(0), ShouldBeTrue)
+			So(func() { sem.ReleaseN(0) }, ShouldNotPanic)
+			So(sem.TryTakeN(1), ShouldBeFalse)
+
+			cancelled, cancelZeroFn := context.WithCancel(context.Background())
+			cancelZeroFn()
+			So(sem.TakeN(cancelled, 0), ShouldBeFalse)
 
+			sem.ReleaseN(3)
+			So(<-waited, ShouldBeTrue)
 		})
 
+		Convey("When cancelled before TakeN", func() {
+			sem := NewWeightedSemaphore(3)
+			ctx, cancelFn := context.WithCancel(context.Background())
+			cancelFn()
+			So(sem.TakeN(ctx, 1), ShouldBeFalse)
+		})
+
+		Convey("When more permits are released than held", func() {
+			sem := NewWeightedSemaphore(3)
+			So(sem.TryTakeN(1), ShouldBeTrue)
+			So(func() { sem.ReleaseN(2) }, ShouldPanic)
+		})
+
+		Convey("When a large waiter is queued, smaller requests do not starve it", func() {
+			sem := NewWeightedSemaphore(10)
+			ctx := context.Background()
+			So(sem.TakeN(ctx, 8), ShouldBeTrue)
+
+			large := takeAsync(ctx, sem, 10)
+			awaitWaiters(sem, 1)
+			small := takeAsync(ctx, sem, 1)
+			awaitWaiters(sem, 2)
+
+			// Capacity is available for the small request, but it is queued behind the large one.
+			So(sem.TryTakeN(1), ShouldBeFalse)
+			select {
+			case <-small:
+				t.FailNow()
+			default:
+			}
+
+			sem.ReleaseN(8)
+			select {
+			case taken := <-large:
+				So(taken, ShouldBeTrue)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			sem.ReleaseN(10)
+			select {
+			case taken := <-small:
+				So(taken, ShouldBeTrue)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When a queued waiter is cancelled the waiters behind it are served", func() {
+			sem := NewWeightedSemaphore(10)
+			So(sem.TakeN(context.Background(), 5), ShouldBeTrue)
+
+			ctx, cancelFn := context.WithCancel(context.Background())
+			large := takeAsync(ctx, sem, 10)
+			awaitWaiters(sem, 1)
+			small := takeAsync(context.Background(), sem, 5)
+			awaitWaiters(sem, 2)
+
+			cancelFn()
+			select {
+			case taken := <-large:
+				So(taken, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			select {
+			case taken := <-small:
+				So(taken, ShouldBeTrue)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
 	})
 }
 
@@ -0,0 +1,126 @@
+// Copyright 2022 Jesse Waite
+
+// send.go is for single send and receive operations which honor done, replacing the
+// hand-written two-stage selects of the done-guard pattern described in notes.md.
+
+package channerics
+
+import "time"
+
+// Send sends v on ch, blocking until it is sent or done is closed, and returns whether
+// v was sent. Done has precedence: if done is already closed, v is never sent.
+func Send[T any](done <-chan struct{}, ch chan<- T, v T) bool {
+	select {
+	case <-done:
+		return false
+	default:
+	}
+
+	select {
+	case ch <- v:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// TrySend sends v on ch if doing so would not block and done is not closed,
+// and returns whether v was sent.
+func TrySend[T any](done <-chan struct{}, ch chan<- T, v T) bool {
+	select {
+	case <-done:
+		return false
+	default:
+	}
+
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+// SendTimeout is Send, which also gives up once timeout has elapsed per clock.
+func SendTimeout[T any](done <-chan struct{}, clock Clock, ch chan<- T, v T, timeout time.Duration) bool {
+	select {
+	case <-done:
+		return false
+	default:
+	}
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ch <- v:
+		return true
+	case <-done:
+		return false
+	case <-timer.C():
+		return false
+	}
+}
+
+// Recv receives from ch, blocking until a value is received, ch is closed, or done is closed.
+// As with a receive, ok reports whether v was received rather than a zero value due to ch's
+// closure. Cancelled reports that done was closed first, in which case nothing was received.
+// Done has precedence: if done is already closed, nothing is received.
+func Recv[T any](done <-chan struct{}, ch <-chan T) (v T, ok bool, cancelled bool) {
+	select {
+	case <-done:
+		return v, false, true
+	default:
+	}
+
+	select {
+	case v, ok = <-ch:
+		return v, ok, false
+	case <-done:
+		return v, false, true
+	}
+}
+
+// TryRecv is Recv if doing so would not block. Blocked reports that no value was ready
+// and done was not closed, in which case nothing was received, as when cancelled.
+func TryRecv[T any](done <-chan struct{}, ch <-chan T) (v T, ok bool, cancelled bool, blocked bool) {
+	select {
+	case <-done:
+		return v, false, true, false
+	default:
+	}
+
+	select {
+	case v, ok = <-ch:
+		return v, ok, false, false
+	default:
+		return v, false, false, true
+	}
+}
+
+// RecvTimeout is Recv, which also gives up once timeout has elapsed per clock. TimedOut
+// reports that the timeout elapsed first, in which case nothing was received, as when cancelled.
+func RecvTimeout[T any](
+	done <-chan struct{},
+	clock Clock,
+	ch <-chan T,
+	timeout time.Duration,
+) (v T, ok bool, cancelled bool, timedOut bool) {
+	select {
+	case <-done:
+		return v, false, true, false
+	default:
+	}
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case v, ok = <-ch:
+		return v, ok, false, false
+	case <-done:
+		return v, false, true, false
+	case <-timer.C():
+		return v, false, false, true
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSendRecv(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	closedDone := func() chan struct{} {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+
+	// recvResult is the result of a Recv variant.
+	type recvResult struct {
+		v         int
+		ok        bool
+		cancelled bool
+		// abandoned is TryRecv's blocked, or RecvTimeout's timedOut.
+		abandoned bool
+	}
+
+	Convey("Send and Recv tests", t, func() {
+		Convey("When done is closed it has precedence over a ready channel", func() {
+			done := closedDone()
+			clock := NewFakeClock(epoch)
+			ch := make(chan int, 1)
+			// Repeat, since an unguarded select would sometimes choose the ready channel.
+			for i := 0; i < 100; i++ {
+				So(Send(done, ch, 1), ShouldBeFalse)
+				So(TrySend(done, ch, 1), ShouldBeFalse)
+				So(SendTimeout(done, clock, ch, 1, time.Second), ShouldBeFalse)
+			}
+			So(len(ch), ShouldEqual, 0)
+
+			ch <- 42
+			for i := 0; i < 100; i++ {
+				_, ok, cancelled := Recv(done, ch)
+				So(ok, ShouldBeFalse)
+				So(cancelled, ShouldBeTrue)
+				_, ok, cancelled, blocked := TryRecv(done, ch)
+				So(ok, ShouldBeFalse)
+				So(cancelled, ShouldBeTrue)
+				So(blocked, ShouldBeFalse)
+				_, ok, cancelled, timedOut := RecvTimeout(done, clock, ch, time.Second)
+				So(ok, ShouldBeFalse)
+				So(cancelled, ShouldBeTrue)
+				So(timedOut, ShouldBeFalse)
+			}
+			So(len(ch), ShouldEqual, 1)
+		})
+
+		Convey("When the channel is ready values are sent and received", func() {
+			clock := NewFakeClock(epoch)
+			ch := make(chan int, 3)
+			So(Send(nil, ch, 1), ShouldBeTrue)
+			So(TrySend(nil, ch, 2), ShouldBeTrue)
+			So(SendTimeout(nil, clock, ch, 3, time.Second), ShouldBeTrue)
+			So(TrySend(nil, ch, 4), ShouldBeFalse)
+
+			v, ok, cancelled := Recv(nil, ch)
+			So(recvResult{v, ok, cancelled, false}, ShouldResemble, recvResult{1, true, false, false})
+			v, ok, cancelled, blocked := TryRecv(nil, ch)
+			So(recvResult{v, ok, cancelled, blocked}, ShouldResemble, recvResult{2, true, false, false})
+			v, ok, cancelled, timedOut := RecvTimeout(nil, clock, ch, time.Second)
+			So(recvResult{v, ok, cancelled, timedOut}, ShouldResemble, recvResult{3, true, false, false})
+
+			// A receive that would block is reported as such, rather than as cancelled.
+			v, ok, cancelled, blocked = TryRecv(nil, ch)
+			So(recvResult{v, ok, cancelled, blocked}, ShouldResemble, recvResult{0, false, false, true})
+		})
+
+		Convey("When blocked, a value which becomes ready is received", func() {
+			ch := make(chan int)
+			go func() {
+				ch <- 1
+				ch <- 2
+			}()
+			v, ok, cancelled := Recv(make(chan struct{}), ch)
+			So(recvResult{v, ok, cancelled, false}, ShouldResemble, recvResult{1, true, false, false})
+			v, ok, cancelled, timedOut := RecvTimeout(make(chan struct{}), NewFakeClock(epoch), ch, time.Second)
+			So(recvResult{v, ok, cancelled, timedOut}, ShouldResemble, recvResult{2, true, false, false})
+
+			sink := make(chan int)
+			go func() {
+				<-sink
+			}()
+			So(Send(make(chan struct{}), sink, 3), ShouldBeTrue)
+		})
+
+		Convey("When the channel is closed receives are not cancelled", func() {
+			ch := make(chan int)
+			close(ch)
+			v, ok, cancelled := Recv(nil, ch)
+			So(recvResult{v, ok, cancelled, false}, ShouldResemble, recvResult{0, false, false, false})
+			v, ok, cancelled, blocked := TryRecv(nil, ch)
+			So(recvResult{v, ok, cancelled, blocked}, ShouldResemble, recvResult{0, false, false, false})
+			v, ok, cancelled, timedOut := RecvTimeout(nil, NewFakeClock(epoch), ch, time.Second)
+			So(recvResult{v, ok, cancelled, timedOut}, ShouldResemble, recvResult{0, false, false, false})
+		})
+
+		Convey("When blocked, closing done unblocks", func() {
+			done := make(chan struct{})
+			clock := NewFakeClock(epoch)
+			ch := make(chan int)
+
+			sent := make(chan bool, 2)
+			received := make(chan recvResult, 2)
+			go func() {
+				sent <- Send(done, ch, 1)
+			}()
+			go func() {
+				sent <- SendTimeout(done, clock, ch, 1, time.Second)
+			}()
+			go func() {
+				v, ok, cancelled := Recv(done, make(chan int))
+				received <- recvResult{v, ok, cancelled, false}
+			}()
+			go func() {
+				v, ok, cancelled, timedOut := RecvTimeout(done, clock, make(chan int), time.Second)
+				received <- recvResult{v, ok, cancelled, timedOut}
+			}()
+
+			// Let each operation block, such that done is not observed by its done-guard.
+			clock.BlockUntil(2)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			close(done)
+			for i := 0; i < 2; i++ {
+				select {
+				case s := <-sent:
+					So(s, ShouldBeFalse)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			for i := 0; i < 2; i++ {
+				select {
+				case r := <-received:
+					So(r, ShouldResemble, recvResult{0, false, true, false})
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+		})
+
+		Convey("When the timeout elapses the operation is abandoned", func() {
+			clock := NewFakeClock(epoch)
+			ch := make(chan int)
+
+			sent := make(chan bool)
+			go func() {
+				sent <- SendTimeout(nil, clock, ch, 1, time.Second)
+			}()
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			select {
+			case s := <-sent:
+				So(s, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			received := make(chan recvResult)
+			go func() {
+				v, ok, cancelled, timedOut := RecvTimeout(nil, clock, ch, time.Second)
+				received <- recvResult{v, ok, cancelled, timedOut}
+			}()
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			select {
+			case r := <-received:
+				So(r, ShouldResemble, recvResult{0, false, false, true})
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+	})
+}
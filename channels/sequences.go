@@ -9,6 +9,14 @@ package channerics
 func Generator[T any](
 	done <-chan struct{},
 	generate func() (T, bool),
+) <-chan T {
+	return generator(goAsync, done, generate)
+}
+
+func generator[T any](
+	spawn spawner,
+	done <-chan struct{},
+	generate func() (T, bool),
 ) <-chan T {
 	ch := make(chan T)
 
@@ -19,7 +27,7 @@ func Generator[T any](
 	// 1) ensure generate is not called if done is closed
 	// 2) check if done afterward, before sending
 	// 3) send or done
-	go func() {
+	spawn(func() {
 		defer close(ch)
 		for {
 			// Since the call to generate is synchronous, we must check if done both before and after.
@@ -47,7 +55,7 @@ func Generator[T any](
 				return
 			}
 		}
-	}()
+	})
 
 	return ch
 }
@@ -56,10 +64,18 @@ func Generator[T any](
 func Repeater[T any](
 	done <-chan struct{},
 	seq []T,
+) <-chan T {
+	return repeater(goAsync, done, seq)
+}
+
+func repeater[T any](
+	spawn spawner,
+	done <-chan struct{},
+	seq []T,
 ) <-chan T {
 	ch := make(chan T)
 
-	go func() {
+	spawn(func() {
 		defer close(ch)
 
 		for {
@@ -77,7 +93,7 @@ func Repeater[T any](
 				}
 			}
 		}
-	}()
+	})
 
 	return ch
 }
@@ -87,15 +103,23 @@ func Repeater[T any](
 func Tee[T any](
 	done <-chan struct{},
 	in <-chan T,
+) (<-chan T, <-chan T) {
+	return tee(goAsync, done, in)
+}
+
+func tee[T any](
+	spawn spawner,
+	done <-chan struct{},
+	in <-chan T,
 ) (<-chan T, <-chan T) {
 	out1 := make(chan T)
 	out2 := make(chan T)
 
-	go func() {
+	spawn(func() {
 		defer close(out1)
 		defer close(out2)
 
-		for v := range OrDone(done, in) {
+		for v := range orDone(spawn, done, in) {
 			var out1, out2 = out1, out2 // intentional shadowing
 			for i := 0; i < 2; i++ {
 				select {
@@ -108,7 +132,7 @@ func Tee[T any](
 				}
 			}
 		}
-	}()
+	})
 
 	return out1, out2
 }
@@ -0,0 +1,121 @@
+// Copyright 2022 Jesse Waite
+
+// shardedfreelist.go is for a free-list whose items are spread across several channels,
+// to reduce contention when a single channel becomes a hot spot.
+
+package channerics
+
+import (
+	"math/rand"
+	"runtime"
+)
+
+// shardedFreeList is a FreeList of several buffered channels. Each Get and Put starts at a
+// randomly chosen shard, then steals from, or spills into, the following shards before
+// allocating or dropping an item, such that concurrent callers are spread across shards.
+// Go exposes no per-P or per-goroutine index, so calls have no affinity to a shard: a Get
+// is no more likely to start at the shard its goroutine last filled than at any other.
+// The random start is drawn from the runtime's per-thread generator, unlike a shared
+// counter, which would itself be a contended cache line.
+type shardedFreeList[T any] struct {
+	shards []chan T
+	newt   func() T
+	reset  func(T)
+	// stats is nil unless counting is enabled.
+	stats *FreeListStats
+}
+
+// NewShardedFreeList returns a free-list of at least the given size, whose items are spread
+// across the given number of shards, calling createFn when T's are created. If shards is not
+// positive, runtime.GOMAXPROCS(0) shards are used. NewShardedFreeList returns ErrInvalidSize
+// if size is not positive.
+func NewShardedFreeList[T any](
+	size int,
+	shards int,
+	createFn func() T,
+	opts FreeListOptions[T],
+) (FreeList[T], error) {
+	if size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	if shards > size {
+		shards = size
+	}
+
+	fl := &shardedFreeList[T]{
+		shards: make([]chan T, shards),
+		newt:   createFn,
+		reset:  opts.Reset,
+	}
+	// Round the shard size up, so the total is at least size.
+	shardSize := (size + shards - 1) / shards
+	for i := range fl.shards {
+		fl.shards[i] = make(chan T, shardSize)
+	}
+	if opts.CountStats {
+		fl.stats = &FreeListStats{}
+	}
+
+	return fl, nil
+}
+
+// start returns the index of the shard at which a call starts. The top-level math/rand
+// functions use the runtime's lock-free generator, unless rand.Seed has been called.
+func (fl *shardedFreeList[T]) start() int {
+	return rand.Intn(len(fl.shards))
+}
+
+// Get returns an item from a random shard, or else a following shard. Returns true if
+// a new allocation occurred and no items were free, false if the returned item is pre-existing.
+func (fl *shardedFreeList[T]) Get() (t T, isNew bool) {
+	start := fl.start()
+	for i := range fl.shards {
+		select {
+		case t = <-fl.shards[(start+i)%len(fl.shards)]:
+			fl.stats.recordGet(false)
+			return
+		default:
+		}
+	}
+
+	t = fl.newt()
+	isNew = true
+	fl.stats.recordGet(isNew)
+	return
+}
+
+// Put returns an item to a random shard, or else a following shard, and returns true.
+// If every shard is at capacity 't' is not added and Put returns false.
+func (fl *shardedFreeList[T]) Put(t T) bool {
+	if fl.reset != nil {
+		fl.reset(t)
+	}
+
+	start := fl.start()
+	for i := range fl.shards {
+		select {
+		case fl.shards[(start+i)%len(fl.shards)] <- t:
+			return true
+		default:
+		}
+	}
+
+	fl.stats.recordDrop()
+	return false
+}
+
+// Drain removes the items currently in every shard, passing each to destroy if non-nil.
+// Items put concurrently may remain.
+func (fl *shardedFreeList[T]) Drain(destroy func(T)) {
+	for _, shard := range fl.shards {
+		drain(shard, destroy)
+	}
+}
+
+// Stats returns a snapshot of the free-list's counters.
+func (fl *shardedFreeList[T]) Stats() FreeListStats {
+	return fl.stats.load()
+}
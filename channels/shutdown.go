@@ -0,0 +1,215 @@
+// Copyright 2022 Jesse Waite
+
+// shutdown.go is for graceful shutdown of servers and other processes upon SIGINT or SIGTERM,
+// in ordered phases, e.g. stop accepting requests, then drain them, then close databases.
+
+package channerics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var ErrHookTimeout = errors.New("shutdown hook did not finish before its phase's deadline")
+
+// ShutdownHook is a named step of a ShutdownPhase. Fn should return promptly once done is
+// closed, which occurs when its phase's deadline passes.
+type ShutdownHook struct {
+	Name string
+	Fn   func(done <-chan struct{}) error
+}
+
+// ShutdownPhase is a set of hooks which run concurrently. Hooks which have not returned
+// when Timeout elapses are reported as failed with ErrHookTimeout, and the next phase begins.
+// A non-positive Timeout waits for the hooks indefinitely.
+type ShutdownPhase struct {
+	Name    string
+	Timeout time.Duration
+	Hooks   []ShutdownHook
+}
+
+// HookFailure reports a hook which returned an error or did not finish in time.
+type HookFailure struct {
+	Phase string
+	Hook  string
+	Err   error
+}
+
+// ShutdownError is returned by Shutdown.Wait when any hooks failed.
+type ShutdownError struct {
+	Failures []HookFailure
+}
+
+func (e *ShutdownError) Error() string {
+	var failures []string
+	for _, f := range e.Failures {
+		failures = append(failures, fmt.Sprintf("%s/%s: %v", f.Phase, f.Hook, f.Err))
+	}
+	return "shutdown hooks failed: " + strings.Join(failures, "; ")
+}
+
+// Shutdown turns the first SIGINT or SIGTERM into a done channel, after which Wait runs the
+// shutdown phases in order. A signal received once shutdown has begun, whether by an earlier
+// signal or by Trigger, force-exits the process with exit status 1, for when shutdown hangs.
+type Shutdown struct {
+	clock   Clock
+	phases  []ShutdownPhase
+	signals chan os.Signal
+	// exit is os.Exit, except in tests.
+	exit func(code int)
+
+	done    chan struct{}
+	trigger sync.Once
+	stop    chan struct{}
+	stopper sync.Once
+
+	// waiter runs the phases once, for every call to Wait, which all return err.
+	waiter sync.Once
+	err    error
+}
+
+// NewShutdown returns a Shutdown with the given phases, which is notified of SIGINT and
+// SIGTERM from this point on, instead of the process being terminated by them.
+// Phase deadlines are measured by clock, which should be SystemClock outside of tests.
+func NewShutdown(clock Clock, phases ...ShutdownPhase) *Shutdown {
+	return newShutdown(clock, os.Exit, phases...)
+}
+
+func newShutdown(clock Clock, exit func(code int), phases ...ShutdownPhase) *Shutdown {
+	s := &Shutdown{
+		clock:   clock,
+		phases:  phases,
+		signals: make(chan os.Signal, 2),
+		exit:    exit,
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	signal.Notify(s.signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go s.watch()
+
+	return s
+}
+
+// watch triggers shutdown upon a signal, or force-exits if shutdown was already triggered.
+func (s *Shutdown) watch() {
+	defer signal.Stop(s.signals)
+
+	for {
+		select {
+		case <-s.signals:
+			select {
+			case <-s.stop:
+				// Wait has returned, so signals are no longer ours to handle.
+				return
+			default:
+			}
+			select {
+			case <-s.done:
+				s.exit(1)
+				return
+			default:
+			}
+			s.Trigger()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Done returns a channel which is closed upon the first signal, or when Trigger is called,
+// to be passed to servers and pipelines as their done channel.
+func (s *Shutdown) Done() <-chan struct{} {
+	return s.done
+}
+
+// Trigger begins shutdown without a signal, e.g. upon a fatal error.
+func (s *Shutdown) Trigger() {
+	s.trigger.Do(func() {
+		close(s.done)
+	})
+}
+
+// Wait blocks until shutdown is triggered, then runs each phase in order, and returns a
+// *ShutdownError reporting every hook which failed, or nil. The phases run only once:
+// further or concurrent calls await the first and return its result. Once Wait returns,
+// signals are no longer intercepted.
+func (s *Shutdown) Wait() error {
+	<-s.done
+	s.waiter.Do(func() {
+		defer s.stopper.Do(func() {
+			close(s.stop)
+		})
+
+		var failures []HookFailure
+		for _, phase := range s.phases {
+			failures = append(failures, s.runPhase(phase)...)
+		}
+
+		if len(failures) > 0 {
+			s.err = &ShutdownError{Failures: failures}
+		}
+	})
+	return s.err
+}
+
+// runPhase runs the phase's hooks concurrently until they return or the phase's deadline
+// passes, and returns the hooks which failed in the order in which they were declared.
+func (s *Shutdown) runPhase(phase ShutdownPhase) []HookFailure {
+	var deadline <-chan time.Time
+	if phase.Timeout > 0 {
+		timer := s.clock.NewTimer(phase.Timeout)
+		defer timer.Stop()
+		deadline = timer.C()
+	}
+
+	phaseDone := make(chan struct{})
+	results := make([]chan error, len(phase.Hooks))
+	for i, hook := range phase.Hooks {
+		// Buffered, such that hooks which time out do not block upon returning.
+		results[i] = make(chan error, 1)
+		go func(hook ShutdownHook, result chan<- error) {
+			result <- hook.Fn(phaseDone)
+		}(hook, results[i])
+	}
+
+	var failures []HookFailure
+	expired := false
+	for i, result := range results {
+		var err error
+		if !expired {
+			select {
+			case err = <-result:
+			case <-deadline:
+				// Signal the remaining hooks, and collect those that have already returned.
+				close(phaseDone)
+				expired = true
+			}
+		}
+		if expired {
+			select {
+			case err = <-result:
+			default:
+				err = ErrHookTimeout
+			}
+		}
+		if err != nil {
+			failures = append(failures, HookFailure{
+				Phase: phase.Name,
+				Hook:  phase.Hooks[i].Name,
+				Err:   err,
+			})
+		}
+	}
+
+	if !expired {
+		close(phaseDone)
+	}
+	return failures
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShutdown(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+	errFailed := errors.New("failed")
+
+	// waitAsync calls s.Wait in a new goroutine, returning a channel yielding its result.
+	waitAsync := func(s *Shutdown) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- s.Wait()
+		}()
+		return result
+	}
+
+	noExit := func(code int) {
+		t.Errorf("unexpected exit: %d", code)
+	}
+
+	Convey("Shutdown tests", t, func() {
+		Convey("When triggered the phases run in order", func() {
+			var mu sync.Mutex
+			var log []string
+			hook := func(name string) ShutdownHook {
+				return ShutdownHook{
+					Name: name,
+					Fn: func(done <-chan struct{}) error {
+						mu.Lock()
+						defer mu.Unlock()
+						log = append(log, name)
+						return nil
+					},
+				}
+			}
+
+			s := newShutdown(SystemClock{}, noExit,
+				ShutdownPhase{Name: "stop accepting", Hooks: []ShutdownHook{hook("listener")}},
+				ShutdownPhase{Name: "drain", Hooks: []ShutdownHook{hook("requests")}},
+				ShutdownPhase{Name: "close", Hooks: []ShutdownHook{hook("database")}},
+			)
+			result := waitAsync(s)
+			select {
+			case <-s.Done():
+				t.FailNow()
+			default:
+			}
+
+			s.Trigger()
+			select {
+			case err := <-result:
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(log, ShouldResemble, []string{"listener", "requests", "database"})
+
+			Convey("Calling Wait again does not rerun the phases", func() {
+				So(s.Wait(), ShouldBeNil)
+				So(log, ShouldResemble, []string{"listener", "requests", "database"})
+			})
+		})
+
+		Convey("When Wait is called repeatedly the first result is returned", func() {
+			var calls int32
+			s := newShutdown(SystemClock{}, noExit,
+				ShutdownPhase{
+					Name: "close",
+					Hooks: []ShutdownHook{
+						{Name: "broken", Fn: func(done <-chan struct{}) error {
+							atomic.AddInt32(&calls, 1)
+							return errFailed
+						}},
+					},
+				},
+			)
+			results := []<-chan error{waitAsync(s), waitAsync(s)}
+			s.Trigger()
+
+			var errs []error
+			for _, result := range results {
+				select {
+				case err := <-result:
+					errs = append(errs, err)
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			errs = append(errs, s.Wait())
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			So(errs[0], ShouldHaveSameTypeAs, &ShutdownError{})
+			So(errs[1], ShouldEqual, errs[0])
+			So(errs[2], ShouldEqual, errs[0])
+		})
+
+		Convey("When NewShutdown is triggered without phases Wait returns nil", func() {
+			s := NewShutdown(SystemClock{})
+			s.Trigger()
+			So(s.Wait(), ShouldBeNil)
+		})
+
+		Convey("When hooks fail or exceed their phase's deadline they are reported", func() {
+			clock := NewFakeClock(epoch)
+			hung := make(chan struct{})
+			defer close(hung)
+
+			s := newShutdown(clock, noExit,
+				ShutdownPhase{
+					Name: "drain",
+					Hooks: []ShutdownHook{
+						{Name: "ok", Fn: func(done <-chan struct{}) error { return nil }},
+						{Name: "broken", Fn: func(done <-chan struct{}) error { return errFailed }},
+					},
+				},
+				ShutdownPhase{
+					Name:    "close",
+					Timeout: time.Second,
+					Hooks: []ShutdownHook{
+						{Name: "hung", Fn: func(done <-chan struct{}) error {
+							<-hung
+							return nil
+						}},
+					},
+				},
+			)
+			result := waitAsync(s)
+			s.Trigger()
+
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+
+			var err error
+			select {
+			case err = <-result:
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(err, ShouldHaveSameTypeAs, &ShutdownError{})
+			failures := err.(*ShutdownError).Failures
+			So(failures, ShouldHaveLength, 2)
+			So(failures[0], ShouldResemble, HookFailure{Phase: "drain", Hook: "broken", Err: errFailed})
+			So(failures[1], ShouldResemble, HookFailure{Phase: "close", Hook: "hung", Err: ErrHookTimeout})
+			So(err.Error(), ShouldContainSubstring, "drain/broken: failed")
+		})
+	})
+}
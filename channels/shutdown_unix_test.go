@@ -0,0 +1,129 @@
+// Copyright 2022 Jesse Waite
+
+//go:build unix
+
+package channerics
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShutdownSignals(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+
+	// signalSelf sends sig to the test process, which the Shutdown under test intercepts.
+	signalSelf := func(sig syscall.Signal) {
+		So(syscall.Kill(syscall.Getpid(), sig), ShouldBeNil)
+	}
+
+	// waitAsync calls s.Wait in a new goroutine, returning a channel yielding its result.
+	waitAsync := func(s *Shutdown) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- s.Wait()
+		}()
+		return result
+	}
+
+	noExit := func(code int) {
+		t.Errorf("unexpected exit: %d", code)
+	}
+
+	Convey("Shutdown signal tests", t, func() {
+		Convey("When signalled shutdown is triggered", func() {
+			ran := make(chan struct{}, 1)
+			s := newShutdown(SystemClock{}, noExit,
+				ShutdownPhase{
+					Name: "close",
+					Hooks: []ShutdownHook{
+						{Name: "database", Fn: func(done <-chan struct{}) error {
+							ran <- struct{}{}
+							return nil
+						}},
+					},
+				},
+			)
+			result := waitAsync(s)
+			select {
+			case <-s.Done():
+				t.FailNow()
+			default:
+			}
+
+			signalSelf(syscall.SIGTERM)
+			select {
+			case err := <-result:
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(len(ran), ShouldEqual, 1)
+		})
+
+		Convey("When a second signal arrives shutdown is forced", func() {
+			exited := make(chan int, 1)
+			hung := make(chan struct{})
+			defer close(hung)
+
+			s := newShutdown(SystemClock{}, func(code int) { exited <- code },
+				ShutdownPhase{
+					Name: "hang",
+					Hooks: []ShutdownHook{
+						{Name: "hung", Fn: func(done <-chan struct{}) error {
+							<-hung
+							return nil
+						}},
+					},
+				},
+			)
+			waitAsync(s)
+
+			signalSelf(syscall.SIGINT)
+			select {
+			case <-s.Done():
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			signalSelf(syscall.SIGINT)
+			select {
+			case code := <-exited:
+				So(code, ShouldEqual, 1)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When shutdown was triggered a single signal forces it", func() {
+			exited := make(chan int, 1)
+			hung := make(chan struct{})
+			defer close(hung)
+
+			s := newShutdown(SystemClock{}, func(code int) { exited <- code },
+				ShutdownPhase{
+					Name: "hang",
+					Hooks: []ShutdownHook{
+						{Name: "hung", Fn: func(done <-chan struct{}) error {
+							<-hung
+							return nil
+						}},
+					},
+				},
+			)
+			waitAsync(s)
+			s.Trigger()
+
+			signalSelf(syscall.SIGTERM)
+			select {
+			case code := <-exited:
+				So(code, ShouldEqual, 1)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+	})
+}
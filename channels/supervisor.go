@@ -0,0 +1,197 @@
+// Copyright 2022 Jesse Waite
+
+// supervisor.go contains Erlang-style supervisor trees, which restart failed child
+// goroutines per a restart strategy.
+
+package channerics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RestartStrategy determines which children a Supervisor restarts when a child fails.
+type RestartStrategy int
+
+const (
+	// OneForOne restarts only the failed child.
+	OneForOne RestartStrategy = iota
+	// OneForAll stops all other running children and restarts them with the failed child.
+	OneForAll
+	// RestForOne stops the running children started after the failed child, and restarts
+	// them along with the failed child.
+	RestForOne
+)
+
+var ErrRestartIntensity = errors.New("supervisor restart intensity exceeded")
+
+// Child is a named goroutine owned by a Supervisor. Run must return promptly once done is
+// closed. Returning nil means the child completed its work and it is not restarted;
+// returning an error means it failed and it is restarted per the supervisor's strategy.
+type Child struct {
+	Name string
+	Run  func(done <-chan struct{}) error
+}
+
+// Supervisor owns a set of children and restarts them per its strategy when they fail.
+// Since Run has the same signature as a Child's Run, supervisors nest to form trees:
+//
+//	sub := NewSupervisor(SystemClock{}, OneForAll, 3, time.Minute, pollers...)
+//	root := NewSupervisor(SystemClock{}, OneForOne, 5, time.Minute,
+//		Child{Name: "db", Run: runDb},
+//		Child{Name: "pollers", Run: sub.Run},
+//	)
+//	err := root.Run(done)
+type Supervisor struct {
+	clock       Clock
+	strategy    RestartStrategy
+	maxRestarts int
+	period      time.Duration
+	children    []Child
+}
+
+// NewSupervisor returns a Supervisor that starts children in the passed order. If more than
+// maxRestarts restarts occur within period, the supervisor gives up: it stops all children and
+// its Run returns ErrRestartIntensity, which a parent supervisor treats as any other failure.
+// Restart times are measured using clock, which should be SystemClock outside of tests.
+func NewSupervisor(
+	clock Clock,
+	strategy RestartStrategy,
+	maxRestarts int,
+	period time.Duration,
+	children ...Child,
+) *Supervisor {
+	return &Supervisor{
+		clock:       clock,
+		strategy:    strategy,
+		maxRestarts: maxRestarts,
+		period:      period,
+		children:    children,
+	}
+}
+
+// Run starts the children in order and supervises them until done is closed, at which point
+// the children are stopped in reverse start order, each being awaited before the next is
+// stopped, and Run returns nil. Run also returns nil once every child has completed, or an
+// error wrapping ErrRestartIntensity if the supervisor gave up.
+// Run may be called again after it returns, e.g. when restarted by a parent supervisor.
+func (sup *Supervisor) Run(done <-chan struct{}) error {
+	// instance is a single run of a child.
+	type instance struct {
+		done   chan struct{}
+		exited chan struct{}
+	}
+	type exit struct {
+		index int
+		inst  *instance
+		err   error
+	}
+
+	exits := make(chan exit)
+	running := make([]*instance, len(sup.children))
+
+	start := func(i int) {
+		inst := &instance{
+			done:   make(chan struct{}),
+			exited: make(chan struct{}),
+		}
+		running[i] = inst
+
+		go func() {
+			err := sup.children[i].Run(inst.done)
+			close(inst.exited)
+			// An instance that was deliberately stopped is not reported.
+			select {
+			case exits <- exit{index: i, inst: inst, err: err}:
+			case <-inst.done:
+			}
+		}()
+	}
+
+	// stop stops the running children at or after index from in reverse start order,
+	// and returns the indices of those that were stopped.
+	stop := func(from int) (stopped []int) {
+		for i := len(running) - 1; i >= from; i-- {
+			if inst := running[i]; inst != nil {
+				close(inst.done)
+				<-inst.exited
+				running[i] = nil
+				stopped = append(stopped, i)
+			}
+		}
+		return
+	}
+
+	numRunning := func() (n int) {
+		for _, inst := range running {
+			if inst != nil {
+				n++
+			}
+		}
+		return
+	}
+
+	for i := range sup.children {
+		start(i)
+	}
+
+	var restarts []time.Time
+	for numRunning() > 0 {
+		// Done-guard: give done precedence over pending exits.
+		select {
+		case <-done:
+			stop(0)
+			return nil
+		default:
+		}
+
+		select {
+		case e := <-exits:
+			if running[e.index] != e.inst {
+				// A stale exit from an instance stopped while it was failing.
+				continue
+			}
+			close(e.inst.done)
+			running[e.index] = nil
+			if e.err == nil {
+				continue
+			}
+
+			now := sup.clock.Now()
+			recent := restarts[:0]
+			for _, t := range restarts {
+				if now.Sub(t) < sup.period {
+					recent = append(recent, t)
+				}
+			}
+			restarts = append(recent, now)
+			if len(restarts) > sup.maxRestarts {
+				stop(0)
+				return fmt.Errorf("%w: child %q: %v", ErrRestartIntensity, sup.children[e.index].Name, e.err)
+			}
+
+			var stopped []int
+			switch sup.strategy {
+			case OneForAll:
+				stopped = stop(0)
+			case RestForOne:
+				stopped = stop(e.index + 1)
+			}
+			restart := map[int]bool{e.index: true}
+			for _, i := range stopped {
+				restart[i] = true
+			}
+			for i := range sup.children {
+				if restart[i] {
+					start(i)
+				}
+			}
+		case <-done:
+			stop(0)
+			return nil
+		}
+	}
+
+	return nil
+}
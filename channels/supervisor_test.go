@@ -0,0 +1,238 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSupervisor(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	errFailed := errors.New("failed")
+
+	// newChild returns a child that logs its starts and stops, and fails when sent to fail.
+	newChild := func(name string, log chan<- string, fail <-chan struct{}) Child {
+		return Child{
+			Name: name,
+			Run: func(done <-chan struct{}) error {
+				log <- "start " + name
+				select {
+				case <-done:
+					log <- "stop " + name
+					return nil
+				case <-fail:
+					log <- "fail " + name
+					return errFailed
+				}
+			},
+		}
+	}
+
+	// expectLog asserts that the next log entries are exactly those expected.
+	expectLog := func(log <-chan string, expected ...string) {
+		for _, entry := range expected {
+			select {
+			case actual := <-log:
+				So(actual, ShouldEqual, entry)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		}
+	}
+
+	// expectLogAnyOrder asserts that the next log entries are those expected, in any order,
+	// since children are started concurrently.
+	expectLogAnyOrder := func(log <-chan string, expected ...string) {
+		actual := []string{}
+		for range expected {
+			select {
+			case entry := <-log:
+				actual = append(actual, entry)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		}
+		So(actual, ShouldHaveLength, len(expected))
+		for _, entry := range expected {
+			So(actual, ShouldContain, entry)
+		}
+	}
+
+	runSupervisor := func(sup *Supervisor, done <-chan struct{}) <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- sup.Run(done)
+		}()
+		return result
+	}
+
+	Convey("Supervisor tests", t, func() {
+		Convey("When done is closed children are stopped in reverse start order", func() {
+			log := make(chan string, 10)
+			done := make(chan struct{})
+			sup := NewSupervisor(SystemClock{}, OneForOne, 1, time.Minute,
+				newChild("a", log, nil),
+				newChild("b", log, nil),
+				newChild("c", log, nil),
+			)
+			result := runSupervisor(sup, done)
+
+			expectLogAnyOrder(log, "start a", "start b", "start c")
+
+			close(done)
+			expectLog(log, "stop c", "stop b", "stop a")
+			select {
+			case err := <-result:
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When all children complete Run returns nil", func() {
+			done := make(chan struct{})
+			defer close(done)
+			sup := NewSupervisor(SystemClock{}, OneForOne, 1, time.Minute,
+				Child{Name: "a", Run: func(<-chan struct{}) error { return nil }},
+				Child{Name: "b", Run: func(<-chan struct{}) error { return nil }},
+			)
+
+			select {
+			case err := <-runSupervisor(sup, done):
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When a child fails under OneForOne only it is restarted", func() {
+			log := make(chan string, 10)
+			fail := make(chan struct{})
+			done := make(chan struct{})
+			defer close(done)
+			sup := NewSupervisor(SystemClock{}, OneForOne, 1, time.Minute,
+				newChild("a", log, nil),
+				newChild("b", log, fail),
+			)
+			runSupervisor(sup, done)
+			expectLogAnyOrder(log, "start a", "start b")
+
+			fail <- struct{}{}
+			expectLog(log, "fail b", "start b")
+		})
+
+		Convey("When a child fails under OneForAll all children are restarted in order", func() {
+			log := make(chan string, 10)
+			fail := make(chan struct{})
+			done := make(chan struct{})
+			defer close(done)
+			sup := NewSupervisor(SystemClock{}, OneForAll, 1, time.Minute,
+				newChild("a", log, fail),
+				newChild("b", log, nil),
+				newChild("c", log, nil),
+			)
+			runSupervisor(sup, done)
+			expectLogAnyOrder(log, "start a", "start b", "start c")
+
+			fail <- struct{}{}
+			expectLog(log, "fail a", "stop c", "stop b")
+			expectLogAnyOrder(log, "start a", "start b", "start c")
+		})
+
+		Convey("When a child fails under RestForOne the children started after it are restarted", func() {
+			log := make(chan string, 10)
+			fail := make(chan struct{})
+			done := make(chan struct{})
+			defer close(done)
+			sup := NewSupervisor(SystemClock{}, RestForOne, 1, time.Minute,
+				newChild("a", log, nil),
+				newChild("b", log, fail),
+				newChild("c", log, nil),
+			)
+			runSupervisor(sup, done)
+			expectLogAnyOrder(log, "start a", "start b", "start c")
+
+			fail <- struct{}{}
+			expectLog(log, "fail b", "stop c")
+			expectLogAnyOrder(log, "start b", "start c")
+		})
+
+		Convey("When restart intensity is exceeded the supervisor gives up", func() {
+			log := make(chan string, 10)
+			fail := make(chan struct{})
+			done := make(chan struct{})
+			defer close(done)
+			sup := NewSupervisor(SystemClock{}, OneForOne, 1, time.Minute,
+				newChild("a", log, nil),
+				newChild("b", log, fail),
+			)
+			result := runSupervisor(sup, done)
+			expectLogAnyOrder(log, "start a", "start b")
+
+			fail <- struct{}{}
+			expectLog(log, "fail b", "start b")
+			fail <- struct{}{}
+			expectLog(log, "fail b", "stop a")
+
+			select {
+			case err := <-result:
+				So(errors.Is(err, ErrRestartIntensity), ShouldBeTrue)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When restarts fall outside the period they do not count toward the intensity", func() {
+			log := make(chan string, 10)
+			fail := make(chan struct{})
+			done := make(chan struct{})
+			defer close(done)
+			clock := NewFakeClock(time.Unix(0, 0))
+			sup := NewSupervisor(clock, OneForOne, 1, time.Minute,
+				newChild("a", log, fail),
+			)
+			runSupervisor(sup, done)
+			expectLog(log, "start a")
+
+			fail <- struct{}{}
+			expectLog(log, "fail a", "start a")
+			clock.Advance(time.Minute)
+			fail <- struct{}{}
+			expectLog(log, "fail a", "start a")
+		})
+
+		Convey("When supervisors are nested shutdown stops the whole tree in reverse order", func() {
+			log := make(chan string, 10)
+			done := make(chan struct{})
+			sub := NewSupervisor(SystemClock{}, OneForOne, 1, time.Minute,
+				newChild("b1", log, nil),
+				newChild("b2", log, nil),
+			)
+			subStarted := make(chan struct{})
+			root := NewSupervisor(SystemClock{}, OneForOne, 1, time.Minute,
+				newChild("a", log, nil),
+				Child{Name: "sub", Run: func(done <-chan struct{}) error {
+					close(subStarted)
+					return sub.Run(done)
+				}},
+			)
+			result := runSupervisor(root, done)
+
+			<-subStarted
+			expectLogAnyOrder(log, "start a", "start b1", "start b2")
+
+			close(done)
+			expectLog(log, "stop b2", "stop b1", "stop a")
+			select {
+			case err := <-result:
+				So(err, ShouldBeNil)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+	})
+}
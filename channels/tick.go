@@ -17,16 +17,34 @@ func NewTicker(
 	done <-chan struct{},
 	duration time.Duration,
 ) chan time.Time {
-	tik := time.NewTicker(duration)
+	return NewTickerWithClock(done, SystemClock{}, duration)
+}
+
+// NewTickerWithClock is NewTicker, taking ticks from clock, e.g. a FakeClock in tests.
+func NewTickerWithClock(
+	done <-chan struct{},
+	clock Clock,
+	duration time.Duration,
+) chan time.Time {
+	return newTicker(goAsync, done, clock, duration)
+}
+
+func newTicker(
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	duration time.Duration,
+) chan time.Time {
+	tik := clock.NewTicker(duration)
 	tok := make(chan time.Time)
 
-	go func() {
+	spawn(func() {
 		defer close(tok)
 		defer tik.Stop()
 
 		for {
 			select {
-			case t := <-tik.C:
+			case t := <-tik.C():
 				select {
 				case tok <- t:
 				case <-done:
@@ -36,7 +54,202 @@ func NewTicker(
 				return
 			}
 		}
-	}()
+	})
 
 	return tok
 }
+
+// Throttle paces the values of in to at most rate values per second, allowing bursts of up
+// to burst values, per a TokenBucket. It returns ErrInvalidRate or ErrInvalidSize if rate or
+// burst are invalid, per NewTokenBucket. The returned channel closes when done is closed, or once in is
+// closed and drained.
+func Throttle[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	rate float64,
+	burst int,
+) (<-chan T, error) {
+	return throttle(goAsync, done, clock, in, rate, burst)
+}
+
+func throttle[T any](
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	rate float64,
+	burst int,
+) (<-chan T, error) {
+	// The bucket's refill goroutine must stop when in is closed, not only when done is.
+	stop := make(chan struct{})
+	bucketDone := eitherDone(spawn, done, stop)
+	tb, err := newTokenBucket(spawn, bucketDone, clock, rate, burst)
+	if err != nil {
+		close(stop)
+		return nil, err
+	}
+
+	out := make(chan T)
+	spawn(func() {
+		defer close(out)
+		defer close(stop)
+
+		// The token stream must also stop with the bucket, or it would await done forever.
+		tokens := tb.tokenStream(spawn, bucketDone)
+		for v := range orDone(spawn, done, in) {
+			if _, ok := <-tokens; !ok {
+				return
+			}
+
+			// Send's done-guard gives done precedence over sending, which may have awaited a token.
+			if !Send(done, out, v) {
+				return
+			}
+		}
+	})
+
+	return out, nil
+}
+
+// Debounce emits the most recent value of in only after no other value has been received
+// for the quiet period, such that a burst of values yields only its last value. When in is
+// closed, any pending value is sent immediately before the returned channel closes.
+// The returned channel closes without sending any pending value if done is closed.
+func Debounce[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	quiet time.Duration,
+) <-chan T {
+	return debounce(goAsync, done, clock, in, quiet)
+}
+
+func debounce[T any](
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	quiet time.Duration,
+) <-chan T {
+	out := make(chan T)
+
+	spawn(func() {
+		defer close(out)
+
+		var pending T
+		var timer Timer
+		// expired is nil, and thus blocks forever, whenever no value is pending.
+		var expired <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		send := func() bool {
+			expired = nil
+			return Send(done, out, pending)
+		}
+
+		for {
+			// Done-guard: done has precedence over in and expired.
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if expired != nil {
+						send()
+					}
+					return
+				}
+
+				pending = v
+				// A new timer, rather than Reset, such that an expiry already pending on the
+				// previous timer's channel is simply abandoned.
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = clock.NewTimer(quiet)
+				expired = timer.C()
+			case <-expired:
+				if !send() {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	})
+
+	return out
+}
+
+// Sample emits the most recent value of in once per interval, if a value was received
+// since the previous sample; intervals in which no value arrived are skipped.
+// The returned channel closes when done is closed, or when in is closed, in which case
+// a value received since the last sample is discarded.
+func Sample[T any](
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	interval time.Duration,
+) <-chan T {
+	return sample(goAsync, done, clock, in, interval)
+}
+
+func sample[T any](
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	in <-chan T,
+	interval time.Duration,
+) <-chan T {
+	out := make(chan T)
+
+	spawn(func() {
+		defer close(out)
+
+		// The ticker must stop when in is closed, not only when done is.
+		stop := make(chan struct{})
+		defer close(stop)
+		ticker := newTicker(spawn, eitherDone(spawn, done, stop), clock, interval)
+		var latest T
+		hasLatest := false
+		for {
+			// Done-guard: done has precedence over in and the ticker.
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				latest = v
+				hasLatest = true
+			case <-ticker:
+				if !hasLatest {
+					continue
+				}
+				hasLatest = false
+
+				if !Send(done, out, latest) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	})
+
+	return out
+}
@@ -3,6 +3,8 @@
 package channerics
 
 import (
+	"math"
+	"runtime"
 	"testing"
 	"time"
 
@@ -11,13 +13,14 @@ import (
 
 func TestTick(t *testing.T) {
 	maxWaitForEffect := time.Duration(50) * time.Millisecond
+	epoch := time.Unix(0, 0)
 
 	Convey("Tick tests", t, func() {
 		Convey("When done is already closed", func() {
 			done := make(chan struct{})
 			duration := time.Millisecond * 10
 			close(done)
-			ticker := NewTicker(done, duration)
+			ticker := NewTickerWithClock(done, NewFakeClock(epoch), duration)
 
 			ok := true
 			select {
@@ -31,16 +34,46 @@ func TestTick(t *testing.T) {
 		Convey("When tick has been sent but done is closed before it is read", func() {
 			done := make(chan struct{})
 			duration := time.Millisecond * 1
-			ticker := NewTicker(done, duration)
+			clock := NewFakeClock(epoch)
+			ticker := NewTickerWithClock(done, clock, duration)
 
-			/// There is no way to deterministically synchronize with the sending of the
-			// tick; this sleep ensures it almost certainly has been sent.
-			<-time.After(duration * 20)
+			clock.Advance(duration)
 			close(done)
 
 			// Drain the ticker; this must be done because there could be one pending
 			// tick sent, non-deterministically chosen over the closure of 'done'.
+			closed := false
+			for !closed {
+				select {
+				case _, ok := <-ticker:
+					closed = !ok
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			So(closed, ShouldBeTrue)
+		})
+
+		Convey("When tick is read -- happy path", func() {
+			done := make(chan struct{})
+			duration := time.Millisecond * 1
+			clock := NewFakeClock(epoch)
+			ticker := NewTickerWithClock(done, clock, duration)
 
+			// Read a few ticks
+			for i := 1; i <= 4; i++ {
+				clock.Advance(duration)
+				select {
+				case tick, ok := <-ticker:
+					So(ok, ShouldBeTrue)
+					So(tick, ShouldEqual, epoch.Add(duration*time.Duration(i)))
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+
+			// ...then close.
+			close(done)
 			ok := true
 			select {
 			case _, ok = <-ticker:
@@ -50,31 +83,327 @@ func TestTick(t *testing.T) {
 			So(ok, ShouldBeFalse)
 		})
 
-		Convey("When tick is read -- happy path", func() {
+		Convey("When NewTicker uses the system clock", func() {
 			done := make(chan struct{})
-			duration := time.Millisecond * 1
-			ticker := NewTicker(done, duration)
+			ticker := NewTicker(done, time.Millisecond)
 
-			// Read a few ticks
-			for i := 0; i < 4; i++ {
+			select {
+			case _, ok := <-ticker:
+				So(ok, ShouldBeTrue)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			close(done)
+			for range ticker {
+			}
+		})
+	})
+}
+
+func TestThrottle(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("Throttle tests", t, func() {
+		Convey("When invalid parameters are passed", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			for _, rate := range []float64{0, math.NaN(), math.Inf(1)} {
+				out, err := Throttle(done, NewFakeClock(epoch), in, rate, 1)
+				So(out, ShouldBeNil)
+				So(err, ShouldBeError, ErrInvalidRate)
+			}
+		})
+
+		Convey("When done is already closed", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			close(done)
+			out, err := Throttle(done, NewFakeClock(epoch), in, 10, 1)
+			So(err, ShouldBeNil)
+
+			ok := true
+			select {
+			case _, ok = <-out:
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When values exceed the burst they are paced", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out, err := Throttle(done, clock, in, 10, 2)
+			So(err, ShouldBeNil)
+
+			go func() {
+				for i := 0; i < 3; i++ {
+					in <- i
+				}
+			}()
+
+			for i := 0; i < 2; i++ {
 				select {
-				case _, ok := <-ticker:
-					So(ok, ShouldBeTrue)
-				case <-time.After(duration * 5):
+				case v := <-out:
+					So(v, ShouldEqual, i)
+				case <-time.After(maxWaitForEffect):
 					t.FailNow()
 				}
 			}
 
-			// ...then close.
+			// The burst is spent, and the clock has not moved.
+			select {
+			case <-out:
+				t.FailNow()
+			case <-time.After(time.Duration(10) * time.Millisecond):
+			}
+
+			v, ok := advanceUntil(t, clock, time.Duration(100)*time.Millisecond, out)
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 2)
+		})
+
+		Convey("When input is closed the output closes -- happy path", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out, err := Throttle(done, clock, in, 10, 1)
+			So(err, ShouldBeNil)
+
+			go func() {
+				defer close(in)
+				for i := 0; i < 3; i++ {
+					in <- i
+				}
+			}()
+
+			results := []int{}
+			for {
+				v, ok := advanceUntil(t, clock, time.Duration(100)*time.Millisecond, out)
+				if !ok {
+					break
+				}
+				results = append(results, v)
+			}
+			So(results, ShouldResemble, []int{0, 1, 2})
+		})
+
+		Convey("When done is closed while awaiting a token the output closes", func() {
+			done := make(chan struct{})
+			in := make(chan int, 2)
+			in <- 1
+			in <- 2
+			out, err := Throttle(done, NewFakeClock(epoch), in, 1, 1)
+			So(err, ShouldBeNil)
+			So(<-out, ShouldEqual, 1)
+
+			// The clock is never advanced, so Throttle awaits a token for 2 until done is closed.
+			time.Sleep(time.Duration(25) * time.Millisecond)
 			close(done)
-			// Drain any pending tick. This must be done since since the inner select
-			// to send the tick may be pending, and the select will non-deterministically
-			// decide whether to send the tick or break on the closure of done.
-			<-ticker
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When done is closed while a value awaits its consumer the output closes", func() {
+			done := make(chan struct{})
+			in := make(chan int, 1)
+			in <- 1
+			out, err := Throttle(done, NewFakeClock(epoch), in, 1, 1)
+			So(err, ShouldBeNil)
+
+			// Let Throttle block on sending 1, then close done instead of reading it.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			close(done)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When input is closed and done never is, no goroutines remain", func() {
+			before := runtime.NumGoroutine()
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out, err := Throttle(nil, clock, in, 10, 1)
+			So(err, ShouldBeNil)
+
+			in <- 1
+			So(<-out, ShouldEqual, 1)
+			// The token stream now holds the refilled token, or awaits one.
+			close(in)
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+
+			remaining := runtime.NumGoroutine()
+			for i := 0; i < 250 && remaining > before; i++ {
+				time.Sleep(time.Millisecond)
+				remaining = runtime.NumGoroutine()
+			}
+			So(remaining, ShouldBeLessThanOrEqualTo, before)
+		})
+	})
+}
+
+func TestDebounce(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	quiet := time.Duration(20) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("Debounce tests", t, func() {
+		Convey("When done is already closed", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			close(done)
+			out := Debounce(done, NewFakeClock(epoch), in, quiet)
 
 			ok := true
 			select {
-			case _, ok = <-ticker:
+			case _, ok = <-out:
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When a burst of values is sent only the last is emitted after the quiet period", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out := Debounce(done, clock, in, quiet)
+
+			for i := 0; i < 5; i++ {
+				in <- i
+			}
+
+			v, ok := advanceUntil(t, clock, quiet, out)
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 4)
+
+			// A subsequent burst is emitted separately.
+			in <- 5
+			v, ok = advanceUntil(t, clock, quiet, out)
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 5)
+		})
+
+		Convey("When input is closed the pending value is flushed", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			out := Debounce(done, NewFakeClock(epoch), in, time.Hour)
+
+			in <- 1
+			in <- 2
+			close(in)
+
+			results := []int{}
+			for v := range out {
+				results = append(results, v)
+			}
+			So(results, ShouldResemble, []int{2})
+		})
+
+		Convey("When done is closed while the value awaits its consumer the output closes", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out := Debounce(done, clock, in, quiet)
+
+			in <- 1
+			clock.BlockUntil(1)
+			clock.Advance(quiet)
+			// Let Debounce block on sending 1, then close done instead of reading it.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			close(done)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestSample(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	interval := time.Duration(10) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("Sample tests", t, func() {
+		Convey("When done is already closed", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			close(done)
+			out := Sample(done, NewFakeClock(epoch), in, interval)
+
+			ok := true
+			select {
+			case _, ok = <-out:
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When several values arrive within an interval the latest is sampled", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out := Sample(done, clock, in, interval)
+
+			for i := 0; i < 3; i++ {
+				in <- i
+			}
+
+			clock.BlockUntil(1)
+			clock.Advance(interval)
+			select {
+			case v := <-out:
+				So(v, ShouldEqual, 2)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+
+			// No new value means no sample.
+			clock.Advance(interval)
+			select {
+			case <-out:
+				t.FailNow()
+			case <-time.After(time.Duration(10) * time.Millisecond):
+			}
+		})
+
+		Convey("When done is closed while the sample awaits its consumer the output closes", func() {
+			done := make(chan struct{})
+			in := make(chan int)
+			clock := NewFakeClock(epoch)
+			out := Sample(done, clock, in, interval)
+
+			in <- 1
+			clock.BlockUntil(1)
+			clock.Advance(interval)
+			// Let Sample block on sending 1, then close done instead of reading it.
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			close(done)
+			time.Sleep(time.Duration(25) * time.Millisecond)
+			_, ok := <-out
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When input is closed the output closes", func() {
+			done := make(chan struct{})
+			defer close(done)
+			in := make(chan int)
+			out := Sample(done, NewFakeClock(epoch), in, interval)
+			close(in)
+
+			ok := true
+			select {
+			case _, ok = <-out:
 			case <-time.After(maxWaitForEffect):
 				t.FailNow()
 			}
@@ -0,0 +1,217 @@
+// Copyright 2022 Jesse Waite
+
+// tokenbucket.go is for token bucket rate limiting.
+
+package channerics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRate = errors.New("rate must be positive and finite")
+	ErrBucketDone  = errors.New("token bucket done")
+)
+
+// TokenBucket is a rate limiter implemented using a buffered channel of tokens, which is
+// refilled at a fixed rate up to its burst capacity. Tokens are not reserved in FIFO order;
+// concurrent callers compete for each token as it is added.
+type TokenBucket struct {
+	tokens chan struct{}
+	done   <-chan struct{}
+}
+
+// NewTokenBucket returns a full TokenBucket that refills rate tokens per second, holding at
+// most burst tokens; rates above one token per nanosecond are treated as exactly that, and
+// rates too low for their interval to be represented refill once per maximum Duration.
+// NewTokenBucket returns ErrInvalidRate if rate is not positive and finite, e.g. NaN.
+// Like NewTicker, the refill goroutine stops when done is closed, after which only the
+// remaining tokens may be taken and waiting callers return ErrBucketDone.
+func NewTokenBucket(
+	done <-chan struct{},
+	clock Clock,
+	rate float64,
+	burst int,
+) (*TokenBucket, error) {
+	return newTokenBucket(goAsync, done, clock, rate, burst)
+}
+
+func newTokenBucket(
+	spawn spawner,
+	done <-chan struct{},
+	clock Clock,
+	rate float64,
+	burst int,
+) (*TokenBucket, error) {
+	// Negated, such that NaN is rejected too.
+	if !(rate > 0) || math.IsInf(rate, 1) {
+		return nil, ErrInvalidRate
+	}
+	if burst <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	tb := &TokenBucket{
+		tokens: make(chan struct{}, burst),
+		done:   done,
+	}
+	for i := 0; i < burst; i++ {
+		tb.tokens <- struct{}{}
+	}
+
+	// Rates beyond one token per nanosecond are clamped, since ticker intervals must be positive,
+	// as are intervals which would overflow a Duration.
+	interval := time.Duration(math.MaxInt64)
+	if nanos := float64(time.Second) / rate; nanos < float64(math.MaxInt64) {
+		interval = time.Duration(nanos)
+	}
+	if interval < 1 {
+		interval = 1
+	}
+	ticker := newTicker(spawn, done, clock, interval)
+	spawn(func() {
+		for range ticker {
+			// Drop the token when the bucket is full.
+			tb.put()
+		}
+	})
+
+	return tb, nil
+}
+
+// put returns a token to the bucket without blocking, discarding it if the bucket is full.
+func (tb *TokenBucket) put() {
+	select {
+	case tb.tokens <- struct{}{}:
+	default:
+	}
+}
+
+// Allow takes a token if one is immediately available and returns true, otherwise it
+// returns false without blocking.
+func (tb *TokenBucket) Allow() bool {
+	select {
+	case <-tb.tokens:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until a token is taken, returning nil, or until ctx is cancelled or the
+// bucket is done and empty, returning ctx.Err() or ErrBucketDone respectively.
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	// Guard: an available token has precedence over the bucket's done, since remaining
+	// tokens may still be taken after done.
+	if tb.Allow() {
+		return nil
+	}
+
+	select {
+	case <-tb.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-tb.done:
+		if tb.Allow() {
+			return nil
+		}
+		return ErrBucketDone
+	}
+}
+
+// Tokens returns a stream that yields a value per token taken from the bucket, for pacing
+// a loop by ranging over it. The stream closes when done or the bucket's done is closed.
+// Note that the stream takes a token before its consumer is ready, and thus holds at most
+// one token; the held token is returned to the bucket when either done channel is closed.
+func (tb *TokenBucket) Tokens(done <-chan struct{}) <-chan struct{} {
+	return tb.tokenStream(goAsync, done)
+}
+
+func (tb *TokenBucket) tokenStream(spawn spawner, done <-chan struct{}) <-chan struct{} {
+	out := make(chan struct{})
+
+	spawn(func() {
+		defer close(out)
+		for {
+			// Done-guard: done has precedence over available tokens.
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			select {
+			case <-tb.tokens:
+				select {
+				case out <- struct{}{}:
+				case <-done:
+					tb.put()
+					return
+				case <-tb.done:
+					tb.put()
+					return
+				}
+			case <-done:
+				return
+			case <-tb.done:
+				return
+			}
+		}
+	})
+
+	return out
+}
+
+// Reservation is a pending claim on n tokens, returned by TokenBucket.Reserve.
+type Reservation struct {
+	ready  chan struct{}
+	cancel chan struct{}
+	closer sync.Once
+}
+
+// Reserve begins taking n tokens from the bucket, which may exceed the bucket's burst size.
+// The reservation's Ready channel closes once all n tokens have been taken. Ready never
+// closes if the bucket is done before all n tokens were taken, so callers should select
+// on the bucket's done channel as well.
+func (tb *TokenBucket) Reserve(n int) *Reservation {
+	r := &Reservation{
+		ready:  make(chan struct{}),
+		cancel: make(chan struct{}),
+	}
+
+	go func() {
+		for taken := 0; taken < n; taken++ {
+			select {
+			case <-tb.tokens:
+			case <-r.cancel:
+				// Return the tokens taken so far.
+				for i := 0; i < taken; i++ {
+					tb.put()
+				}
+				return
+			case <-tb.done:
+				return
+			}
+		}
+		close(r.ready)
+	}()
+
+	return r
+}
+
+// Ready returns a channel that closes once all the reserved tokens have been taken.
+func (r *Reservation) Ready() <-chan struct{} {
+	return r.ready
+}
+
+// Cancel abandons the reservation and returns any tokens it has taken to the bucket,
+// unless the reservation is already ready, in which case Cancel has no effect.
+// Cancel may be called repeatedly from multiple go routines.
+func (r *Reservation) Cancel() {
+	r.closer.Do(func() { close(r.cancel) })
+}
@@ -0,0 +1,281 @@
+// Copyright 2022 Jesse Waite
+
+package channerics
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTokenBucket(t *testing.T) {
+	maxWaitForEffect := time.Duration(250) * time.Millisecond
+	epoch := time.Unix(0, 0)
+
+	Convey("TokenBucket tests", t, func() {
+		Convey("When invalid parameters are passed", func() {
+			done := make(chan struct{})
+			defer close(done)
+
+			for _, rate := range []float64{0, -1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+				tb, err := NewTokenBucket(done, NewFakeClock(epoch), rate, 1)
+				So(tb, ShouldBeNil)
+				So(err, ShouldBeError, ErrInvalidRate)
+			}
+
+			tb, err := NewTokenBucket(done, NewFakeClock(epoch), 1, 0)
+			So(tb, ShouldBeNil)
+			So(err, ShouldBeError, ErrInvalidSize)
+		})
+
+		Convey("When Allow is called the burst is available immediately", func() {
+			done := make(chan struct{})
+			defer close(done)
+			// The fake clock ensures no refill occurs during the test.
+			tb, err := NewTokenBucket(done, NewFakeClock(epoch), 1, 3)
+			So(err, ShouldBeNil)
+
+			for i := 0; i < 3; i++ {
+				So(tb.Allow(), ShouldBeTrue)
+			}
+			So(tb.Allow(), ShouldBeFalse)
+		})
+
+		Convey("When the bucket is empty it is refilled at the rate", func() {
+			done := make(chan struct{})
+			defer close(done)
+			clock := NewFakeClock(epoch)
+			tb, err := NewTokenBucket(done, clock, 1000, 1)
+			So(err, ShouldBeNil)
+			So(tb.Allow(), ShouldBeTrue)
+			So(tb.Allow(), ShouldBeFalse)
+
+			// Await the refill goroutine's ticker.
+			clock.BlockUntil(1)
+			ctx, cancelFn := context.WithTimeout(context.Background(), maxWaitForEffect)
+			defer cancelFn()
+			for i := 0; i < 3; i++ {
+				clock.Advance(time.Millisecond)
+				So(tb.Wait(ctx), ShouldBeNil)
+			}
+		})
+
+		Convey("When Wait is cancelled", func() {
+			done := make(chan struct{})
+			defer close(done)
+			tb, err := NewTokenBucket(done, NewFakeClock(epoch), 1, 1)
+			So(err, ShouldBeNil)
+			So(tb.Allow(), ShouldBeTrue)
+
+			ctx, cancelFn := context.WithCancel(context.Background())
+			cancelFn()
+			So(tb.Wait(ctx), ShouldBeError, context.Canceled)
+		})
+
+		Convey("When the bucket is done remaining tokens may be taken, then Wait fails", func() {
+			done := make(chan struct{})
+			tb, err := NewTokenBucket(done, NewFakeClock(epoch), 1, 1)
+			So(err, ShouldBeNil)
+			close(done)
+
+			So(tb.Wait(context.Background()), ShouldBeNil)
+			So(tb.Wait(context.Background()), ShouldBeError, ErrBucketDone)
+		})
+
+		Convey("When Tokens is ranged over", func() {
+			bucketDone := make(chan struct{})
+			defer close(bucketDone)
+			clock := NewFakeClock(epoch)
+			tb, err := NewTokenBucket(bucketDone, clock, 1000, 2)
+			So(err, ShouldBeNil)
+
+			done := make(chan struct{})
+			tokens := tb.Tokens(done)
+			for i := 0; i < 4; i++ {
+				_, ok := advanceUntil(t, clock, time.Millisecond, tokens)
+				So(ok, ShouldBeTrue)
+			}
+
+			close(done)
+			closed := false
+			for !closed {
+				select {
+				case _, ok := <-tokens:
+					closed = !ok
+				case <-time.After(maxWaitForEffect):
+					t.FailNow()
+				}
+			}
+			So(closed, ShouldBeTrue)
+		})
+
+		Convey("When the bucket is done while Tokens holds a token, the token is returned", func() {
+			bucketDone := make(chan struct{})
+			tb, err := NewTokenBucket(bucketDone, NewFakeClock(epoch), 1, 1)
+			So(err, ShouldBeNil)
+
+			// The stream takes the only token, and blocks sending it since nothing reads.
+			tokens := tb.Tokens(nil)
+			for i := 0; i < 250 && len(tb.tokens) > 0; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			So(len(tb.tokens), ShouldEqual, 0)
+
+			close(bucketDone)
+			select {
+			case _, ok := <-tokens:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(len(tb.tokens), ShouldEqual, 1)
+		})
+
+		Convey("When done is closed while Tokens holds a token, it is returned unless the bucket is full", func() {
+			bucketDone := make(chan struct{})
+			defer close(bucketDone)
+			clock := NewFakeClock(epoch)
+			tb, err := NewTokenBucket(bucketDone, clock, 1, 1)
+			So(err, ShouldBeNil)
+
+			done := make(chan struct{})
+			tokens := tb.Tokens(done)
+			for i := 0; i < 250 && len(tb.tokens) > 0; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			// Refill the bucket, such that the held token is dropped.
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			for i := 0; i < 250 && len(tb.tokens) == 0; i++ {
+				time.Sleep(time.Millisecond)
+			}
+
+			close(done)
+			select {
+			case _, ok := <-tokens:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+			So(len(tb.tokens), ShouldEqual, 1)
+		})
+
+		Convey("When the bucket is done and empty, Tokens closes", func() {
+			bucketDone := make(chan struct{})
+			tb, err := NewTokenBucket(bucketDone, NewFakeClock(epoch), 1, 1)
+			So(err, ShouldBeNil)
+			So(tb.Allow(), ShouldBeTrue)
+
+			tokens := tb.Tokens(nil)
+			close(bucketDone)
+			select {
+			case _, ok := <-tokens:
+				So(ok, ShouldBeFalse)
+			case <-time.After(maxWaitForEffect):
+				t.FailNow()
+			}
+		})
+
+		Convey("When done is already closed Tokens closes without taking a token", func() {
+			bucketDone := make(chan struct{})
+			defer close(bucketDone)
+			tb, err := NewTokenBucket(bucketDone, NewFakeClock(epoch), 1, 1)
+			So(err, ShouldBeNil)
+
+			done := make(chan struct{})
+			close(done)
+			_, ok := <-tb.Tokens(done)
+			So(ok, ShouldBeFalse)
+			So(len(tb.tokens), ShouldEqual, 1)
+		})
+
+		Convey("When the rate exceeds one token per nanosecond it is clamped", func() {
+			done := make(chan struct{})
+			defer close(done)
+			clock := NewFakeClock(epoch)
+			tb, err := NewTokenBucket(done, clock, 2e9, 1)
+			So(err, ShouldBeNil)
+			So(tb.Allow(), ShouldBeTrue)
+
+			// The refill ticker is created with a positive interval, rather than panicking.
+			clock.BlockUntil(1)
+			clock.Advance(time.Nanosecond)
+			ctx, cancelFn := context.WithTimeout(context.Background(), maxWaitForEffect)
+			defer cancelFn()
+			So(tb.Wait(ctx), ShouldBeNil)
+		})
+
+		Convey("When the rate is too low for its interval to be a Duration it is clamped", func() {
+			done := make(chan struct{})
+			defer close(done)
+			clock := NewFakeClock(epoch)
+			tb, err := NewTokenBucket(done, clock, 1e-12, 1)
+			So(err, ShouldBeNil)
+			So(tb.Allow(), ShouldBeTrue)
+
+			// The interval is the maximum Duration, rather than overflowing to the minimum.
+			clock.BlockUntil(1)
+			clock.Advance(100 * 365 * 24 * time.Hour)
+			time.Sleep(time.Duration(10) * time.Millisecond)
+			So(tb.Allow(), ShouldBeFalse)
+		})
+
+		Convey("When more tokens are reserved than the burst", func() {
+			done := make(chan struct{})
+			defer close(done)
+			clock := NewFakeClock(epoch)
+			tb, err := NewTokenBucket(done, clock, 1000, 2)
+			So(err, ShouldBeNil)
+
+			r := tb.Reserve(5)
+			_, ok := advanceUntil(t, clock, time.Millisecond, r.Ready())
+			So(ok, ShouldBeFalse)
+			// Cancel after ready has no effect.
+			r.Cancel()
+		})
+
+		Convey("When the bucket is done before a reservation is ready", func() {
+			done := make(chan struct{})
+			tb, err := NewTokenBucket(done, NewFakeClock(epoch), 1, 1)
+			So(err, ShouldBeNil)
+
+			r := tb.Reserve(2)
+			close(done)
+			select {
+			case <-r.Ready():
+				t.FailNow()
+			case <-time.After(time.Duration(10) * time.Millisecond):
+			}
+		})
+
+		Convey("When a reservation is cancelled its tokens are returned", func() {
+			done := make(chan struct{})
+			defer close(done)
+			tb, err := NewTokenBucket(done, NewFakeClock(epoch), 1, 2)
+			So(err, ShouldBeNil)
+
+			r := tb.Reserve(3)
+			// Await the reservation taking both tokens.
+			for i := 0; i < 100 && len(tb.tokens) > 0; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			So(len(tb.tokens), ShouldEqual, 0)
+
+			r.Cancel()
+			r.Cancel()
+			for i := 0; i < 100 && len(tb.tokens) < 2; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			So(len(tb.tokens), ShouldEqual, 2)
+
+			select {
+			case <-r.Ready():
+				t.FailNow()
+			default:
+			}
+		})
+	})
+}
@@ -1,6 +1,6 @@
 module github.com/niceyeti/channerics
 
-go 1.18
+go 1.20
 
 require (
 	github.com/smartystreets/goconvey v1.7.2